@@ -0,0 +1,349 @@
+package simpleforce
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An access token handed out by one of the OAuth token endpoints.
+type token struct {
+	accessToken  string
+	instanceUrl  string
+	refreshToken string
+}
+
+// How long a renewal may take. It runs on behalf of every request waiting on it, so it isn't bound
+// to any one of their contexts.
+const renewTimeout = time.Minute
+
+// The session shared by every copy of a Force, along with the means to log in again.
+type authState struct {
+	mu      sync.RWMutex
+	session string
+	// The renewal in progress, if any.
+	renewing *renewal
+	// Fetches a new token, or is nil if the session was given to us and can't be renewed.
+	login func(context.Context) (token, error)
+}
+
+// A renewal shared by every request that found the session expired while it ran.
+type renewal struct {
+	// Closed once the renewal is over, after err is set.
+	done chan struct{}
+	err  error
+}
+
+// Returns the session to send with the next request. It never waits on a renewal.
+func (a *authState) current() string {
+	if a == nil {
+		return ""
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.session
+}
+
+// Logs in again, unless another goroutine already replaced the stale session. Only one login runs at
+// a time; requests that find the session expired meanwhile wait on it, or give up when their own
+// context is done. Returns false if there's no way to get a new session.
+func (a *authState) renew(ctx context.Context, stale string) (bool, error) {
+	if a == nil || a.login == nil {
+		return false, nil
+	}
+	a.mu.Lock()
+	if a.session != stale {
+		a.mu.Unlock()
+		return true, nil
+	}
+	r := a.renewing
+	if r == nil {
+		r = &renewal{done: make(chan struct{})}
+		a.renewing = r
+		go a.run(context.WithoutCancel(ctx), r)
+	}
+	a.mu.Unlock()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	if r.err != nil {
+		return false, r.err
+	}
+	return true, nil
+}
+
+func (a *authState) run(ctx context.Context, r *renewal) {
+	ctx, cancel := context.WithTimeout(ctx, renewTimeout)
+	defer cancel()
+	t, err := a.login(ctx)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err == nil {
+		a.session = t.accessToken
+	}
+	r.err = err
+	a.renewing = nil
+	close(r.done)
+}
+
+// Logs in with the OAuth 2.0 JWT bearer flow, as the given user of the connected app identified by
+// consumerKey. The assertion is signed with the PEM encoded RSA private key whose certificate was
+// uploaded to the connected app; both PKCS #1 and PKCS #8 keys are accepted. loginUrl is both where
+// the token is requested and the audience of the assertion, such as https://login.salesforce.com.
+// A fresh assertion is signed whenever the session needs renewing.
+func NewWithJWT(loginUrl, consumerKey, username string, privateKeyPEM []byte, opts ...Option) (Force, error) {
+	return NewWithJWTContext(context.Background(), loginUrl, consumerKey, username, privateKeyPEM, opts...)
+}
+
+// Like NewWithJWT, but the login request is bound to the given context.
+func NewWithJWTContext(ctx context.Context, loginUrl, consumerKey, username string, privateKeyPEM []byte, opts ...Option) (Force, error) {
+	key, err := parseRSAPrivateKey(privateKeyPEM)
+	if err != nil {
+		return Force{}, err
+	}
+	return newWithLogin(ctx, func(ctx context.Context, client *http.Client) (token, error) {
+		assertion, err := signJWT(key, map[string]interface{}{
+			"iss": consumerKey,
+			"sub": username,
+			"aud": loginUrl,
+			"exp": time.Now().Add(3 * time.Minute).Unix(),
+		})
+		if err != nil {
+			return token{}, err
+		}
+		return requestToken(ctx, client, loginUrl, url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		})
+	}, opts)
+}
+
+func parseRSAPrivateKey(privateKeyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("simpleforce: no PEM block found in private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("simpleforce: private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// Encodes and signs the given claims as an RS256 JSON Web Token.
+func signJWT(key *rsa.PrivateKey, claims map[string]interface{}) (string, error) {
+	claimBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + enc.EncodeToString(claimBytes)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// Logs in with an OAuth refresh token, such as one obtained through NewWithWebLogin. The refresh
+// token is kept to renew the session when it expires.
+func NewWithRefreshToken(loginUrl, clientId, clientSecret, refreshToken string, opts ...Option) (Force, error) {
+	return NewWithRefreshTokenContext(context.Background(), loginUrl, clientId, clientSecret, refreshToken, opts...)
+}
+
+// Like NewWithRefreshToken, but the login request is bound to the given context.
+func NewWithRefreshTokenContext(ctx context.Context, loginUrl, clientId, clientSecret, refreshToken string, opts ...Option) (Force, error) {
+	return newWithLogin(ctx, refreshLogin(loginUrl, clientId, clientSecret, refreshToken), opts)
+}
+
+// Logs in with the OAuth web server flow, for command line tools acting on behalf of a person. A
+// listener is started at redirectUrl, which must be on localhost and match the connected app's
+// callback URL, such as http://localhost:1717/oauth/callback. The URL to open in a browser is written
+// to out, and once the person approves access the authorization code is exchanged for a session.
+// If the connected app hands out refresh tokens, one is kept to renew the session.
+func NewWithWebLogin(loginUrl, clientId, clientSecret, redirectUrl string, out io.Writer, opts ...Option) (Force, error) {
+	return NewWithWebLoginContext(context.Background(), loginUrl, clientId, clientSecret, redirectUrl, out, opts...)
+}
+
+// Like NewWithWebLogin, but gives up waiting for the callback, and the code exchange, once the given
+// context is done.
+func NewWithWebLoginContext(ctx context.Context, loginUrl, clientId, clientSecret, redirectUrl string, out io.Writer, opts ...Option) (Force, error) {
+	redirect, err := url.Parse(redirectUrl)
+	if err != nil {
+		return Force{}, err
+	}
+	if host := redirect.Hostname(); host != "localhost" && host != "127.0.0.1" && host != "::1" {
+		return Force{}, errors.New("simpleforce: redirect URL must be on localhost, got " + host)
+	}
+	f := New("", "", opts...)
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		return Force{}, err
+	}
+	state := hex.EncodeToString(stateBytes)
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return Force{}, err
+	}
+	type callback struct {
+		code string
+		err  error
+	}
+	results := make(chan callback, 1)
+	path := redirect.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var cb callback
+		switch {
+		case q.Get("error") != "":
+			cb.err = &APIError{StatusCode: http.StatusUnauthorized, ErrorCode: q.Get("error"), Message: q.Get("error_description")}
+		case q.Get("state") != state:
+			cb.err = errors.New("simpleforce: OAuth callback state does not match")
+		default:
+			cb.code = q.Get("code")
+		}
+		if cb.err != nil {
+			http.Error(w, cb.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Logged in, you can close this window.")
+		}
+		select {
+		case results <- cb:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authorize := url.Values{
+		"response_type": {"code"},
+		"client_id":     {clientId},
+		"redirect_uri":  {redirectUrl},
+		"state":         {state},
+	}
+	fmt.Fprintf(out, "Open this URL in your browser to log in:\n%v\n", loginUrl+"/services/oauth2/authorize?"+authorize.Encode())
+
+	var cb callback
+	select {
+	case cb = <-results:
+	case <-ctx.Done():
+		return Force{}, ctx.Err()
+	}
+	if cb.err != nil {
+		return Force{}, cb.err
+	}
+	t, err := requestToken(ctx, f.client, loginUrl, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {cb.code},
+		"client_id":     {clientId},
+		"client_secret": {clientSecret},
+		"redirect_uri":  {redirectUrl},
+	})
+	if err != nil {
+		return Force{}, err
+	}
+	var login func(context.Context, *http.Client) (token, error)
+	if t.refreshToken != "" {
+		login = refreshLogin(loginUrl, clientId, clientSecret, t.refreshToken)
+	}
+	return f.withToken(t, login), nil
+}
+
+// Returns a login function that trades a refresh token for a new session.
+func refreshLogin(loginUrl, clientId, clientSecret, refreshToken string) func(context.Context, *http.Client) (token, error) {
+	return func(ctx context.Context, client *http.Client) (token, error) {
+		return requestToken(ctx, client, loginUrl, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {clientId},
+			"client_secret": {clientSecret},
+			"refresh_token": {refreshToken},
+		})
+	}
+}
+
+// Builds a Force from the token the given login function returns, keeping the function around to
+// renew the session later. Logging in goes through the same client as every other request.
+func newWithLogin(ctx context.Context, login func(context.Context, *http.Client) (token, error), opts []Option) (Force, error) {
+	f := New("", "", opts...)
+	t, err := login(ctx, f.client)
+	if err != nil {
+		return Force{}, err
+	}
+	return f.withToken(t, login), nil
+}
+
+// Points a newly created Force at the instance and session in the given token.
+func (f Force) withToken(t token, login func(context.Context, *http.Client) (token, error)) Force {
+	f.instance = t.instanceUrl
+	f.auth.session = t.accessToken
+	if login != nil {
+		client := f.client
+		f.auth.login = func(ctx context.Context) (token, error) {
+			return login(ctx, client)
+		}
+	}
+	return f
+}
+
+// Posts an OAuth grant to the token endpoint under loginUrl.
+func requestToken(ctx context.Context, client *http.Client, loginUrl string, vals url.Values) (token, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", loginUrl+"/services/oauth2/token", strings.NewReader(vals.Encode()))
+	if err != nil {
+		return token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return token{}, err
+	}
+	defer resp.Body.Close()
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return token{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return token{}, newAPIError(resp.StatusCode, respBytes)
+	}
+	var grant struct {
+		AccessToken  string `json:"access_token"`
+		InstanceUrl  string `json:"instance_url"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(respBytes, &grant); err != nil {
+		return token{}, err
+	}
+	return token{grant.AccessToken, grant.InstanceUrl, grant.RefreshToken}, nil
+}
@@ -0,0 +1,150 @@
+package simpleforce_test
+
+import (
+	"bufio"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/jakebasile/simpleforce"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewWithJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/oauth2/token" {
+			t.Errorf("unexpected path %v", r.URL.Path)
+		}
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("unexpected grant type %q", r.FormValue("grant_type"))
+		}
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if len(parts) != 3 {
+			t.Errorf("malformed assertion %q", r.FormValue("assertion"))
+			http.Error(w, "malformed assertion", http.StatusBadRequest)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], sig); err != nil {
+			t.Errorf("bad signature: %v", err)
+		}
+		claimBytes, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims struct {
+			Iss string
+			Sub string
+			Aud string
+			Exp int64
+		}
+		json.Unmarshal(claimBytes, &claims)
+		if claims.Iss != "consumer" || claims.Sub != "jake@example.com" || claims.Aud != srv.URL {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+		if claims.Exp <= time.Now().Unix() {
+			t.Errorf("assertion already expired: %v", claims.Exp)
+		}
+		fmt.Fprintf(w, `{"access_token":"jwt-session","instance_url":"%v"}`, srv.URL)
+	}))
+	defer srv.Close()
+
+	if _, err := simpleforce.NewWithJWT(srv.URL, "consumer", "jake@example.com", keyPEM); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := simpleforce.NewWithJWT(srv.URL, "consumer", "jake@example.com", []byte("not a key")); err == nil {
+		t.Error("expected an error for a bad key")
+	}
+}
+
+func TestNewWithRefreshToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"expired access/refresh token"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"session","instance_url":"http://%v"}`, r.Host)
+	}))
+	defer srv.Close()
+
+	if _, err := simpleforce.NewWithRefreshToken(srv.URL, "id", "secret", "refresh"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := simpleforce.NewWithRefreshToken(srv.URL, "id", "secret", "stale"); err == nil {
+		t.Error("expected an error for a bad refresh token")
+	}
+}
+
+func TestNewWithWebLogin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "authorization_code" || r.FormValue("code") != "the-code" {
+			t.Errorf("unexpected grant %v", r.Form)
+		}
+		fmt.Fprintf(w, `{"access_token":"session","instance_url":"http://%v","refresh_token":"refresh"}`, r.Host)
+	}))
+	defer srv.Close()
+
+	// Find a free port for the callback listener.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirectUrl := "http://" + l.Addr().String() + "/oauth/callback"
+	l.Close()
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := simpleforce.NewWithWebLogin(srv.URL, "id", "secret", redirectUrl, pw)
+		done <- err
+	}()
+
+	lines := bufio.NewScanner(pr)
+	lines.Scan()
+	lines.Scan()
+	authorize, err := url.Parse(lines.Text())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go io.Copy(ioutil.Discard, pr)
+	if authorize.Query().Get("redirect_uri") != redirectUrl {
+		t.Errorf("unexpected authorize URL %v", authorize)
+	}
+	resp, err := http.Get(redirectUrl + "?code=the-code&state=" + authorize.Query().Get("state"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewWithWebLoginContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := simpleforce.NewWithWebLoginContext(ctx, "https://login.salesforce.com", "id", "secret", "http://127.0.0.1:0/callback", ioutil.Discard)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected to give up waiting for the callback, got %v", err)
+	}
+}
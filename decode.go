@@ -0,0 +1,352 @@
+package simpleforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// One batch of query results. Records are kept as raw field values until they're decoded into the
+// destination type.
+type queryResult struct {
+	TotalSize      int                          `json:"totalSize"`
+	Done           bool                         `json:"done"`
+	NextRecordsUrl string                       `json:"nextRecordsUrl"`
+	Records        []map[string]json.RawMessage `json:"records"`
+}
+
+// The fields of a struct type that take part in encoding and decoding, worked out once per type.
+type fieldPlan struct {
+	Field
+	index int
+}
+
+var fieldPlans sync.Map
+
+// Returns the field plan for a struct type, parsing its tags the first time the type is seen.
+func planFor(t reflect.Type) []fieldPlan {
+	if plan, ok := fieldPlans.Load(t); ok {
+		return plan.([]fieldPlan)
+	}
+	var plan []fieldPlan
+	for i := 0; i < t.NumField(); i++ {
+		if info, ok := ParseField(t.Field(i)); ok {
+			plan = append(plan, fieldPlan{info, i})
+		}
+	}
+	actual, _ := fieldPlans.LoadOrStore(t, plan)
+	return actual.([]fieldPlan)
+}
+
+var (
+	recordType = reflect.TypeOf(Record{})
+	mapType    = reflect.TypeOf(map[string]interface{}{})
+)
+
+// Reports whether records can be decoded into the given type: a struct, a Record, or a
+// map[string]interface{}.
+func isDecodable(t reflect.Type) bool {
+	return t == recordType || t == mapType || t.Kind() == reflect.Struct
+}
+
+func (f Force) unmarshal(ctx context.Context, source *queryResult, dest interface{}) error {
+	sliceVal := reflect.ValueOf(dest).Elem()
+	return f.unmarshalRecords(ctx, source, sliceVal)
+}
+
+// Appends every record in a query result to the given slice, following nextRecordsUrl until the
+// result is done. Child relationships in a record are query results of their own.
+func (f Force) unmarshalRecords(ctx context.Context, source *queryResult, sliceVal reflect.Value) error {
+	elemType := sliceVal.Type().Elem()
+	for {
+		sliceVal.Grow(len(source.Records))
+		for _, record := range source.Records {
+			n := sliceVal.Len()
+			sliceVal.SetLen(n + 1)
+			elem := sliceVal.Index(n)
+			elem.Set(reflect.Zero(elemType))
+			if err := f.unmarshalRecord(ctx, record, elem); err != nil {
+				return err
+			}
+		}
+		if source.Done || source.NextRecordsUrl == "" {
+			return nil
+		}
+		var err error
+		source, err = f.queryPage(ctx, f.instance+source.NextRecordsUrl)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Decodes a single record into val, which must be addressable and of a type isDecodable accepts.
+func (f Force) unmarshalRecord(ctx context.Context, source map[string]json.RawMessage, val reflect.Value) error {
+	switch val.Type() {
+	case recordType:
+		return f.unmarshalDynamicRecord(ctx, source, val.Addr().Interface().(*Record))
+	case mapType:
+		m := make(map[string]interface{}, len(source))
+		for name, raw := range source {
+			v, err := f.unmarshalDynamicValue(ctx, raw, mapType, name == "attributes")
+			if err != nil {
+				return fmt.Errorf("simpleforce: decoding %v: %v", name, err)
+			}
+			m[name] = v
+		}
+		val.Set(reflect.ValueOf(m))
+		return nil
+	default:
+		return f.unmarshalIndividualObject(ctx, source, val, nil)
+	}
+}
+
+func (f Force) unmarshalDynamicRecord(ctx context.Context, source map[string]json.RawMessage, r *Record) error {
+	r.Fields = make(map[string]interface{}, len(source))
+	for name, raw := range source {
+		if name == "attributes" {
+			if !isNull(raw) {
+				if err := json.Unmarshal(raw, &r.Attributes); err != nil {
+					return fmt.Errorf("simpleforce: decoding %v: %v", name, err)
+				}
+			}
+			continue
+		}
+		v, err := f.unmarshalDynamicValue(ctx, raw, recordType, false)
+		if err != nil {
+			return fmt.Errorf("simpleforce: decoding %v: %v", name, err)
+		}
+		r.Fields[name] = v
+	}
+	return nil
+}
+
+// Decodes a field value of a record whose schema isn't known ahead of time. Parent relationships are
+// decoded as elemType, or a pointer to it for Records, and child relationships as a slice of it. Any
+// other value, or every value when plain is set, is decoded the way encoding/json decodes into an
+// interface{}.
+func (f Force) unmarshalDynamicValue(ctx context.Context, raw json.RawMessage, elemType reflect.Type, plain bool) (interface{}, error) {
+	var v interface{}
+	if plain || len(raw) == 0 || raw[0] != '{' {
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	_, hasRecords := obj["records"]
+	_, hasDone := obj["done"]
+	_, hasAttributes := obj["attributes"]
+	switch {
+	case hasRecords && hasDone:
+		var children queryResult
+		if err := json.Unmarshal(raw, &children); err != nil {
+			return nil, err
+		}
+		sliceVal := reflect.New(reflect.SliceOf(elemType)).Elem()
+		if err := f.unmarshalRecords(ctx, &children, sliceVal); err != nil {
+			return nil, err
+		}
+		return sliceVal.Interface(), nil
+	case hasAttributes:
+		valPtr := reflect.New(elemType)
+		if err := f.unmarshalRecord(ctx, obj, valPtr.Elem()); err != nil {
+			return nil, err
+		}
+		if elemType == mapType {
+			return valPtr.Elem().Interface(), nil
+		}
+		return valPtr.Interface(), nil
+	}
+	// a compound field, such as an address.
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+// Decodes a single record into val, which must be an addressable struct. Fields missing from the
+// record are treated as NULL. Parents holds the types of the records val was reached through.
+func (f Force) unmarshalIndividualObject(ctx context.Context, source map[string]json.RawMessage, val reflect.Value, parents []reflect.Type) error {
+	parents = append(parents, val.Type())
+	for _, info := range planFor(val.Type()) {
+		field := val.Field(info.index)
+		raw := source[info.Name]
+		switch {
+		case info.Parent:
+			objType := field.Type().Elem()
+			if isNull(raw) && (len(parents) > MaxRelationshipDepth || containsType(parents, objType)) {
+				// a NULL parent is still allocated, but not forever when the types refer back to
+				// themselves.
+				continue
+			}
+			var obj map[string]json.RawMessage
+			if !isNull(raw) {
+				if err := json.Unmarshal(raw, &obj); err != nil {
+					return fmt.Errorf("simpleforce: decoding %v: %v", info.Name, err)
+				}
+			}
+			objPtr := reflect.New(objType)
+			var err error
+			if objType == recordType {
+				err = f.unmarshalRecord(ctx, obj, objPtr.Elem())
+			} else {
+				err = f.unmarshalIndividualObject(ctx, obj, objPtr.Elem(), parents)
+			}
+			if err != nil {
+				return err
+			}
+			field.Set(objPtr)
+		case info.Children:
+			if raw == nil {
+				continue
+			}
+			objSlice := reflect.New(field.Type()).Elem()
+			if !isNull(raw) {
+				var children queryResult
+				if err := json.Unmarshal(raw, &children); err != nil {
+					return fmt.Errorf("simpleforce: decoding %v: %v", info.Name, err)
+				}
+				if err := f.unmarshalRecords(ctx, &children, objSlice); err != nil {
+					return err
+				}
+			}
+			field.Set(objSlice)
+		default:
+			if err := unmarshalValue(raw, field); err != nil {
+				return fmt.Errorf("simpleforce: decoding %v: %v", info.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func containsType(ts []reflect.Type, t reflect.Type) bool {
+	for _, other := range ts {
+		if other == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Decodes a single field value. Unmarshalers are handed the raw value, NULL included. Otherwise NULL
+// leaves the field at its zero value, which for pointers and the Null types means nil or invalid.
+// Values of the wrong JSON type leave the field at its zero value too, except for whole numbers.
+func unmarshalValue(raw json.RawMessage, field reflect.Value) error {
+	if u, ok := asUnmarshaler(field); ok {
+		if raw == nil {
+			raw = json.RawMessage("null")
+		}
+		return u.UnmarshalForce(raw)
+	}
+	if isNull(raw) {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.Bool:
+		field.SetBool(string(raw) == "true")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intVal, err := jsonInt(raw)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(intVal) {
+			return fmt.Errorf("%v overflows %v", intVal, field.Type())
+		}
+		field.SetInt(intVal)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		intVal, err := jsonInt(raw)
+		if err != nil {
+			return err
+		}
+		if intVal < 0 || field.OverflowUint(uint64(intVal)) {
+			return fmt.Errorf("%v overflows %v", intVal, field.Type())
+		}
+		field.SetUint(uint64(intVal))
+	case reflect.Float32, reflect.Float64:
+		field.SetFloat(jsonFloat(raw))
+	case reflect.String:
+		field.SetString(jsonString(raw))
+	case reflect.Ptr:
+		ptr := reflect.New(field.Type().Elem())
+		if err := unmarshalValue(raw, ptr.Elem()); err != nil {
+			return err
+		}
+		field.Set(ptr)
+	case reflect.Struct:
+		switch field.Type() {
+		case timeType:
+			t, err := parseTime(jsonString(raw))
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(t))
+		case nullStringType:
+			field.Set(reflect.ValueOf(NullString{jsonString(raw), true}))
+		case nullFloatType:
+			field.Set(reflect.ValueOf(NullFloat{jsonFloat(raw), true}))
+		case nullTimeType:
+			t, err := parseTime(jsonString(raw))
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(NullTime{t, true}))
+		}
+	}
+	return nil
+}
+
+// Reports whether a value is NULL or missing altogether.
+func isNull(raw json.RawMessage) bool {
+	return raw == nil || string(raw) == "null"
+}
+
+// Reads a whole number, which Salesforce sometimes sends with a fractional part, such as 25.0.
+func jsonInt(raw json.RawMessage) (int64, error) {
+	if intVal, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+		return intVal, nil
+	}
+	floatVal, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a number", raw)
+	}
+	return int64(floatVal), nil
+}
+
+// Reads a number, or zero if the value isn't one.
+func jsonFloat(raw json.RawMessage) float64 {
+	floatVal, _ := strconv.ParseFloat(string(raw), 64)
+	return floatVal
+}
+
+// Reads a string, or the empty string if the value isn't one. Strings without escapes are sliced
+// straight out of the raw value.
+func jsonString(raw json.RawMessage) string {
+	if len(raw) < 2 || raw[0] != '"' {
+		return ""
+	}
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return string(raw[1 : len(raw)-1])
+	}
+	var s string
+	json.Unmarshal(raw, &s)
+	return s
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(DateTimeFormat, s); err == nil {
+		// it's a datetime string, probably!
+		return t, nil
+	} else if t, err = time.Parse(salesforceDateTimeFormat, s); err == nil {
+		// a datetime the way Salesforce writes them, with no colon in the zone.
+		return t, nil
+	}
+	// nope, it's a date string!
+	return time.Parse(DateFormat, s)
+}
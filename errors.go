@@ -0,0 +1,78 @@
+package simpleforce
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// An error reported by Salesforce. Every request that gets a response outside the 2xx range returns
+// one of these, whether it came from the REST API or the OAuth token endpoint.
+type APIError struct {
+	// The HTTP status code of the response.
+	StatusCode int
+	// The Salesforce error code, such as INVALID_FIELD or INVALID_SESSION_ID. For OAuth failures this
+	// is the OAuth error, such as invalid_grant.
+	ErrorCode string
+	Message   string
+	// The fields the error relates to, if any.
+	Fields []string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("simpleforce: %v %v: %v", e.StatusCode, e.ErrorCode, e.Message)
+	if len(e.Fields) > 0 {
+		msg += " (" + strings.Join(e.Fields, ", ") + ")"
+	}
+	return msg
+}
+
+// Reports whether err means the session is no longer valid and a new one is needed.
+func IsSessionExpired(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.ErrorCode == "INVALID_SESSION_ID" || apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// Reports whether err means the requested record or resource does not exist.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.ErrorCode == "NOT_FOUND" || apiErr.StatusCode == http.StatusNotFound
+}
+
+// Builds an APIError from an error response body. The REST API sends an array of errors, of which the
+// first is kept; the OAuth endpoints send a single object. Anything else, such as an HTML error page
+// from a proxy, ends up as the message.
+func newAPIError(status int, body []byte) *APIError {
+	e := &APIError{StatusCode: status}
+	var restErrs []struct {
+		ErrorCode string   `json:"errorCode"`
+		Message   string   `json:"message"`
+		Fields    []string `json:"fields"`
+	}
+	var oauthErr struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &restErrs); err == nil && len(restErrs) > 0 {
+		e.ErrorCode = restErrs[0].ErrorCode
+		e.Message = restErrs[0].Message
+		e.Fields = restErrs[0].Fields
+	} else if err := json.Unmarshal(body, &oauthErr); err == nil && oauthErr.Error != "" {
+		e.ErrorCode = oauthErr.Error
+		e.Message = oauthErr.ErrorDescription
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+		if e.Message == "" {
+			e.Message = http.StatusText(status)
+		}
+	}
+	return e
+}
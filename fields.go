@@ -0,0 +1,143 @@
+package simpleforce
+
+import (
+	"reflect"
+	"strings"
+)
+
+// How a struct field maps onto a Salesforce field. By default a field's API name is its Go name, but
+// a force tag can change that and more:
+//
+//	type Account struct {
+//		Revenue float64   `force:"Annual_Revenue__c"` // a different API name
+//		Owner   *User     `force:"Owner__r"`          // a different relationship name
+//		Total   float64   `force:",readonly"`         // read and queried, but never written
+//		Notes   string    `force:",omitempty"`        // not written by Create or Upsert when empty
+//		Renewal time.Time `force:",date"`             // a Date field, written as YYYY-MM-DD
+//		Cache   string    `force:"-"`                 // ignored altogether
+//	}
+//
+// Reading query results, writing records and generating queries with the query package all use the
+// same mapping.
+type Field struct {
+	// The API name of the field or relationship.
+	Name      string
+	OmitEmpty bool
+	ReadOnly  bool
+	// Set for a Date field, which Salesforce only accepts in DateFormat. Times are otherwise written
+	// in DateTimeFormat.
+	Date bool
+	// Set for a pointer to a record, which holds a parent relationship such as Account on a Contact.
+	Parent bool
+	// Set for a slice of records, which holds a child relationship such as Contacts on an Account.
+	Children bool
+}
+
+// The most parent relationships a query can follow from a record. Up to 5 can be traversed, as in
+// A.B.C.D.E.Name.
+const MaxRelationshipDepth = 5
+
+// Returns the mapping for the given struct field, or false if the field is unexported or tagged "-"
+// and so has no Salesforce counterpart.
+func ParseField(field reflect.StructField) (Field, bool) {
+	if field.PkgPath != "" {
+		return Field{}, false
+	}
+	tag := field.Tag.Get("force")
+	if tag == "-" {
+		return Field{}, false
+	}
+	parts := strings.Split(tag, ",")
+	info := Field{Name: parts[0]}
+	if info.Name == "" {
+		info.Name = field.Name
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		info.Parent = isRecord(t.Elem())
+	} else if t.Kind() == reflect.Slice {
+		info.Children = isRecord(t.Elem())
+	}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "omitempty":
+			info.OmitEmpty = true
+		case "readonly":
+			info.ReadOnly = true
+		case "date":
+			info.Date = true
+		}
+	}
+	return info, true
+}
+
+// Implemented by field types that decode themselves, such as a currency or picklist type. The raw
+// JSON of the field's value is passed in, and is null when the field is NULL.
+type Unmarshaler interface {
+	UnmarshalForce(data []byte) error
+}
+
+// Implemented by field types that encode themselves. MarshalForce returns the JSON to send for the
+// field's value.
+type Marshaler interface {
+	MarshalForce() ([]byte, error)
+}
+
+var (
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+)
+
+// Reports whether the given type is decoded as a whole record rather than a single field value.
+func isRecord(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || isValueStruct(t) {
+		return false
+	}
+	return !reflect.PtrTo(t).Implements(unmarshalerType) && !reflect.PtrTo(t).Implements(marshalerType)
+}
+
+// Returns the field as an Unmarshaler if it, or a pointer to it, is one. Nil pointers are left to the
+// caller to fill in first.
+func asUnmarshaler(field reflect.Value) (Unmarshaler, bool) {
+	if field.Kind() != reflect.Ptr && field.CanAddr() && field.Addr().Type().Implements(unmarshalerType) {
+		return field.Addr().Interface().(Unmarshaler), true
+	}
+	if field.Kind() == reflect.Ptr && !field.IsNil() && field.Type().Implements(unmarshalerType) {
+		return field.Interface().(Unmarshaler), true
+	}
+	return nil, false
+}
+
+// Returns the field as a Marshaler if it, or a pointer to it, is one. Nil pointers are not, and are
+// sent as NULL.
+func asMarshaler(field reflect.Value) (Marshaler, bool) {
+	if field.Kind() == reflect.Ptr && field.IsNil() {
+		return nil, false
+	}
+	if field.Type().Implements(marshalerType) {
+		return field.Interface().(Marshaler), true
+	}
+	if field.CanAddr() && field.Addr().Type().Implements(marshalerType) {
+		return field.Addr().Interface().(Marshaler), true
+	}
+	return nil, false
+}
+
+// Returns the sObject type name for the given struct type. This is the name of the struct, unless it
+// has a blank field tagged with the type to use, as in:
+//
+//	type Invoice struct {
+//		_      struct{} `sobject:"Invoice__c"`
+//		Amount float64
+//	}
+func SObjectType(t reflect.Type) string {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "_" {
+			if name := field.Tag.Get("sobject"); name != "" {
+				return name
+			}
+		}
+	}
+	return t.Name()
+}
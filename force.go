@@ -9,181 +9,315 @@ package simpleforce
 
 import (
 	"bytes"
-	"github.com/bitly/go-simplejson"
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"time"
 )
 
 const (
 	DateFormat     = "2006-01-02"
 	DateTimeFormat = time.RFC3339Nano
+	// The REST API version used unless another is chosen with WithVersion.
+	DefaultVersion = "27.0"
+
+	salesforceDateTimeFormat = "2006-01-02T15:04:05.000-0700"
 )
 
+// Copies of a Force share its session, so a session renewed through one copy is used by all of them.
 type Force struct {
-	session string
-	url     string
+	instance   string
+	version    string
+	client     *http.Client
+	middleware []Middleware
+	timeout    time.Duration
+	retry      RetryPolicy
+	auth       *authState
+	usage      *usageState
+	limiter    *limiter
 }
 
-// Returns a new Force object with the given login credentials. This object is the main
-// point of entry for all your Force.com needs.
-func New(session, url string) Force {
-	return Force{
-		session,
-		url,
-	}
-}
+// Configures a Force as it is created.
+type Option func(*Force)
 
-func NewWithCredentials(loginUrl, consumerKey, consumerSecret, username, password string) (Force, error) {
-	resp, err := http.PostForm(loginUrl+"/services/oauth2/token", url.Values{
-		"grant_type":    {"password"},
-		"client_id":     {consumerKey},
-		"client_secret": {consumerSecret},
-		"username":      {username},
-		"password":      {password},
-	})
-	if err != nil {
-		return Force{}, err
+// Returns a new Force object with the given login credentials. This object is the main
+// point of entry for all your Force.com needs. The url is that of your instance, such as
+// https://na1.salesforce.com. For compatibility it may also include the REST API path, such as
+// https://na1.salesforce.com/services/data/v27.0, in which case that version is used.
+func New(session, url string, opts ...Option) Force {
+	instance, version := url, DefaultVersion
+	if i := strings.Index(url, "/services/data/v"); i >= 0 {
+		instance, version = url[:i], strings.TrimSuffix(url[i+len("/services/data/v"):], "/")
 	}
-	respBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return Force{}, err
+	f := Force{
+		instance: instance,
+		version:  version,
+		client:   http.DefaultClient,
+		retry:    DefaultRetryPolicy(),
+		auth:     &authState{session: session},
+		usage:    &usageState{},
 	}
-	respJson, err := simplejson.NewJson(respBytes)
-	if err != nil {
-		return Force{}, err
+	for _, opt := range opts {
+		opt(&f)
 	}
-	session := respJson.Get("access_token").MustString()
-	url := respJson.Get("instance_url").MustString() + "/services/data/v27.0"
-	return New(session, url), err
+	f.client = f.wrapClient()
+	return f
 }
 
-func NewFromEnvironment() (Force, error) {
-	return NewWithCredentials(os.Getenv("SF_LOGIN_URL"), os.Getenv("SF_CLIENT_ID"), os.Getenv("SF_CLIENT_SECRET"), os.Getenv("SF_USERNAME"), os.Getenv("SF_PASSWORD")+os.Getenv("SF_TOKEN"))
+// Logs in with the OAuth username-password flow. The credentials are kept so that the session can be
+// renewed automatically when it expires.
+func NewWithCredentials(loginUrl, consumerKey, consumerSecret, username, password string, opts ...Option) (Force, error) {
+	return NewWithCredentialsContext(context.Background(), loginUrl, consumerKey, consumerSecret, username, password, opts...)
 }
 
-func (f Force) authorizeRequest(method, urlStr string, body io.Reader) (*http.Request, error) {
-	r, err := http.NewRequest(method, urlStr, body)
+// Like NewWithCredentials, but the login request is bound to the given context.
+func NewWithCredentialsContext(ctx context.Context, loginUrl, consumerKey, consumerSecret, username, password string, opts ...Option) (Force, error) {
+	return newWithLogin(ctx, func(ctx context.Context, client *http.Client) (token, error) {
+		return requestToken(ctx, client, loginUrl, url.Values{
+			"grant_type":    {"password"},
+			"client_id":     {consumerKey},
+			"client_secret": {consumerSecret},
+			"username":      {username},
+			"password":      {password},
+		})
+	}, opts)
+}
+
+func NewFromEnvironment(opts ...Option) (Force, error) {
+	return NewFromEnvironmentContext(context.Background(), opts...)
+}
+
+// Like NewFromEnvironment, but the login request is bound to the given context.
+func NewFromEnvironmentContext(ctx context.Context, opts ...Option) (Force, error) {
+	return NewWithCredentialsContext(ctx, os.Getenv("SF_LOGIN_URL"), os.Getenv("SF_CLIENT_ID"), os.Getenv("SF_CLIENT_SECRET"), os.Getenv("SF_USERNAME"), os.Getenv("SF_PASSWORD")+os.Getenv("SF_TOKEN"), opts...)
+}
+
+func (f Force) authorizeRequest(ctx context.Context, method, urlStr string, body io.Reader) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, method, urlStr, body)
 	if err != nil {
 		return nil, err
 	}
-	r.Header.Add("Authorization", "Bearer "+f.session)
+	r.Header.Add("Authorization", "Bearer "+f.auth.current())
 	return r, nil
 }
 
 // Run a raw SOQL query string. This will fill the given destination slice with the results of your query.
+// Results spanning more than one batch are fetched batch by batch until all have been read. The slice
+// may hold structs, or Records or map[string]interface{} values when the fields aren't known ahead of
+// time.
 func (f Force) Query(query string, dest interface{}) error {
+	return f.QueryContext(context.Background(), query, dest)
+}
+
+// Like Query, but every request made for the query, including those for later batches, is bound to
+// the given context.
+func (f Force) QueryContext(ctx context.Context, query string, dest interface{}) error {
+	destType := reflect.TypeOf(dest)
+	if destType == nil || destType.Kind() != reflect.Ptr || destType.Elem().Kind() != reflect.Slice || !isDecodable(destType.Elem().Elem()) {
+		return errors.New("simpleforce: Query needs a pointer to a slice of structs, Records or maps")
+	}
 	vals := url.Values{}
 	vals.Set("q", query)
-	url := f.url + "/query?" + vals.Encode()
-	req, err := f.authorizeRequest("GET", url, bytes.NewBufferString(""))
+	page, err := f.queryPage(ctx, f.dataUrl()+"/query?"+vals.Encode())
 	if err != nil {
 		return err
 	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+	return f.unmarshal(ctx, page, dest)
+}
+
+// An iterator over the results of a query, for result sets too large to hold in memory at once.
+// Only one batch of records is held at a time; the next batch is fetched when the current one runs
+// out.
+type Iter struct {
+	f     Force
+	ctx   context.Context
+	url   string
+	page  *queryResult
+	index int
+	err   error
+}
+
+// Runs a raw SOQL query string, returning an iterator over its results. No request is made until the
+// first call to Next. Stop early by simply not calling Next again.
+//
+//	it := f.QueryIter("SELECT Subject FROM Task")
+//	var t Task
+//	for it.Next(&t) {
+//		fmt.Println(t.Subject)
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle the error
+//	}
+func (f Force) QueryIter(query string) *Iter {
+	return f.QueryIterContext(context.Background(), query)
+}
+
+// Like QueryIter, but every batch is fetched under the given context.
+func (f Force) QueryIterContext(ctx context.Context, query string) *Iter {
+	vals := url.Values{}
+	vals.Set("q", query)
+	return &Iter{
+		f:   f,
+		ctx: ctx,
+		url: f.dataUrl() + "/query?" + vals.Encode(),
 	}
-	respBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+}
+
+// Decodes the next record into the given pointer to a struct, Record or map, fetching the next batch of results if
+// needed. Returns false once there are no more records or an error occurred; check Err to tell which.
+func (it *Iter) Next(dest interface{}) bool {
+	if it.err != nil {
+		return false
+	}
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() || !isDecodable(destVal.Elem().Type()) {
+		it.err = errors.New("simpleforce: Next needs a pointer to a struct, Record or map")
+		return false
+	}
+	for it.page == nil || it.index >= len(it.page.Records) {
+		if it.url == "" {
+			return false
+		}
+		page, err := it.f.queryPage(it.ctx, it.url)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = page
+		it.index = 0
+		if page.Done || page.NextRecordsUrl == "" {
+			it.url = ""
+		} else {
+			it.url = it.f.instance + page.NextRecordsUrl
+		}
+	}
+	destVal.Elem().Set(reflect.Zero(destVal.Elem().Type()))
+	if err := it.f.unmarshalRecord(it.ctx, it.page.Records[it.index], destVal.Elem()); err != nil {
+		it.err = err
+		return false
+	}
+	it.index++
+	return true
+}
+
+// Returns the total number of records the query matched, as reported by the most recent batch.
+func (it *Iter) TotalSize() int {
+	if it.page == nil {
+		return 0
+	}
+	return it.page.TotalSize
+}
+
+// Returns the error, if any, that stopped iteration.
+func (it *Iter) Err() error {
+	return it.err
+}
+
+// Fetches a single batch of query results.
+func (f Force) queryPage(ctx context.Context, urlStr string) (*queryResult, error) {
+	page := &queryResult{}
+	if _, err := f.sendJson(ctx, "GET", urlStr, nil, page); err != nil {
+		return nil, err
 	}
-	respJson, err := simplejson.NewJson(respBytes)
+	return page, nil
+}
+
+// Returns the root of the REST API for the version this Force uses, which every resource URL is
+// built from.
+func (f Force) dataUrl() string {
+	return f.instance + "/services/data/v" + f.version
+}
+
+// Sends an authorized request with the given value encoded as its JSON body, decoding the response
+// body into dest unless dest is nil or the response has no content. Returns the response status. Any
+// status outside the 2xx range is returned as an *APIError.
+func (f Force) sendJson(ctx context.Context, method, urlStr string, body, dest interface{}) (int, error) {
+	var reqBody []byte
+	contentType := ""
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reqBody = b
+		contentType = "application/json"
+	}
+	status, respBytes, err := f.send(ctx, method, urlStr, reqBody, contentType)
 	if err != nil {
-		return err
+		return status, err
+	}
+	if dest != nil && len(respBytes) > 0 {
+		if err := json.Unmarshal(respBytes, dest); err != nil {
+			return status, err
+		}
 	}
-	err = unmarshal(respJson, dest)
-	return err
+	return status, nil
 }
 
-func (f Force) Create(interface{}) (interface{}, error) {
+// Returns the client requests are sent with, which is only unset for the zero Force.
+func (f Force) httpClient() *http.Client {
+	if f.client == nil {
+		return http.DefaultClient
+	}
+	return f.client
+}
 
+// Sends an authorized request and reads the whole response, retrying transient failures as the
+// Force's RetryPolicy allows.
+func (f Force) send(ctx context.Context, method, urlStr string, body []byte, contentType string) (int, []byte, error) {
+	return f.retry.do(ctx, method, func() (int, []byte, error) {
+		return f.sendAuthorized(ctx, method, urlStr, body, contentType)
+	})
 }
 
-func unmarshal(source *simplejson.Json, dest interface{}) error {
-	sliceValPtr := reflect.ValueOf(dest)
-	sliceVal := sliceValPtr.Elem()
-	elemType := reflect.TypeOf(dest).Elem().Elem()
-	for i := 0; i < source.Get("totalSize").MustInt(); i++ {
-		v := source.Get("records").GetIndex(i)
-		val, err := unmarshalIndividualObject(v, elemType)
+// Sends an authorized request once. If the session turns out to have expired and this Force knows how
+// to log in again, it does so and sends the request once more.
+func (f Force) sendAuthorized(ctx context.Context, method, urlStr string, body []byte, contentType string) (int, []byte, error) {
+	for retried := false; ; retried = true {
+		req, err := f.authorizeRequest(ctx, method, urlStr, bytes.NewReader(body))
 		if err != nil {
-			return err
+			return 0, nil, err
 		}
-		sliceVal.Set(reflect.Append(sliceVal, val))
-	}
-	return nil
-}
-
-func unmarshalIndividualObject(source *simplejson.Json, valType reflect.Type) (reflect.Value, error) {
-	valPtr := reflect.New(valType)
-	val := reflect.Indirect(valPtr)
-	for f := 0; f < valType.NumField(); f++ {
-		field := val.Field(f)
-		switch field.Kind() {
-		case reflect.Bool:
-			boolVal := source.Get(valType.Field(f).Name).MustBool()
-			field.SetBool(boolVal)
-		case reflect.Int:
-			intVal := source.Get(valType.Field(f).Name).MustInt64()
-			field.SetInt(intVal)
-		case reflect.Int64:
-			intVal := source.Get(valType.Field(f).Name).MustInt64()
-			field.SetInt(intVal)
-		case reflect.Float32:
-			floatVal := source.Get(valType.Field(f).Name).MustFloat64()
-			field.SetFloat(floatVal)
-		case reflect.Float64:
-			floatVal := source.Get(valType.Field(f).Name).MustFloat64()
-			field.SetFloat(floatVal)
-		case reflect.String:
-			strVal := source.Get(valType.Field(f).Name).MustString()
-			field.SetString(strVal)
-		case reflect.Struct:
-			strVal := source.Get(valType.Field(f).Name).MustString()
-			if valType.Field(f).Type.Name() == "Time" {
-				if t, err := time.Parse(DateTimeFormat, strVal); err == nil {
-					// it's a datetime string, probably!
-					field.Set(reflect.ValueOf(t))
-				} else if t, err = time.Parse(DateFormat, strVal); err == nil {
-					// nope, it's a date string!
-					field.Set(reflect.ValueOf(t))
-				} else {
-					return val, err
-				}
-			}
-		case reflect.Ptr:
-			objJson := source.Get(valType.Field(f).Name)
-			if objJson != nil {
-				objType := valType.Field(f).Type.Elem()
-				objVal, err := unmarshalIndividualObject(objJson, objType)
-				if err != nil {
-					return val, err
-				}
-				field.Set(objVal.Addr())
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		session := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		release, err := f.limiter.acquire(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		resp, err := f.httpClient().Do(req)
+		if err != nil {
+			release()
+			return 0, nil, err
+		}
+		f.usage.record(resp.Header.Get("Sforce-Limit-Info"))
+		respBytes, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		release()
+		if err != nil {
+			return 0, nil, err
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return resp.StatusCode, respBytes, nil
+		}
+		apiErr := newAPIError(resp.StatusCode, respBytes)
+		if !retried && resp.StatusCode == http.StatusUnauthorized && IsSessionExpired(apiErr) {
+			renewed, err := f.auth.renew(ctx, session)
+			if err != nil {
+				return resp.StatusCode, nil, err
 			}
-		case reflect.Slice:
-			objJson := source.Get(valType.Field(f).Name).Get("records")
-			length := source.Get(valType.Field(f).Name).Get("totalSize").MustInt()
-			if objJson != nil {
-				elemType := field.Type().Elem()
-				objSlicePtr := reflect.New(field.Type())
-				objSlice := reflect.Indirect(objSlicePtr)
-				for i := 0; i < length; i++ {
-					o := objJson.GetIndex(i)
-					obj, err := unmarshalIndividualObject(o, elemType)
-					if err != nil {
-						return val, err
-					}
-					objSlice.Set(reflect.Append(objSlice, obj))
-				}
-				field.Set(objSlice)
+			if renewed {
+				continue
 			}
 		}
+		return resp.StatusCode, nil, apiErr
 	}
-	return val, nil
 }
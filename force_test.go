@@ -1,22 +1,45 @@
 package simpleforce_test
 
 import (
-	"github.com/jakebasile/simpleforce"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/jakebasile/simpleforce"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
 var (
 	force simpleforce.Force
+	live  bool
 )
 
 func init() {
+	// Tests that talk to a real org only run when one is configured.
+	if os.Getenv("SF_LOGIN_URL") == "" {
+		return
+	}
 	var err error
 	force, err = simpleforce.NewFromEnvironment()
 	if err != nil {
 		panic(err)
 	}
+	live = true
+}
+
+func requireLive(tb testing.TB) {
+	if !live {
+		tb.Skip("SF_LOGIN_URL not set, skipping test against a live org")
+	}
 }
 
 type Account struct {
@@ -31,6 +54,7 @@ type Contact struct {
 }
 
 func BenchmarkQuery(b *testing.B) {
+	requireLive(b)
 	for i := 0; i < b.N; i++ {
 		var cs []Contact
 		force.Query(`
@@ -57,6 +81,7 @@ func ExampleForce_Query() {
 }
 
 func TestDate(t *testing.T) {
+	requireLive(t)
 	type Contact struct {
 		Birthdate time.Time
 	}
@@ -71,6 +96,7 @@ func TestDate(t *testing.T) {
 	}
 }
 func TestRawQuery(t *testing.T) {
+	requireLive(t)
 	var cs []Contact
 	force.Query("SELECT FirstName FROM Contact WHERE FirstName<>'' LIMIT 1", &cs)
 	for _, c := range cs {
@@ -82,6 +108,7 @@ func TestRawQuery(t *testing.T) {
 }
 
 func TestChildObjects(t *testing.T) {
+	requireLive(t)
 	type Contact struct {
 		Name string
 	}
@@ -104,3 +131,1137 @@ func TestChildObjects(t *testing.T) {
 		}
 	}
 }
+
+func TestCreate(t *testing.T) {
+	type Invoice struct {
+		_      struct{} `sobject:"Invoice__c"`
+		Id     string
+		Amount float64
+		Paid   bool
+	}
+
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/services/data/v27.0/sobjects/Invoice__c/" {
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer session" {
+			t.Errorf("missing authorization, got %q", r.Header.Get("Authorization"))
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"a01000000000001","success":true,"errors":[]}`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	id, err := f.Create(&Invoice{Id: "ignored", Amount: 12.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "a01000000000001" {
+		t.Errorf("got id %q", id)
+	}
+	if _, ok := body["Id"]; ok {
+		t.Error("Id should not be sent on create")
+	}
+	if body["Amount"] != 12.5 || body["Paid"] != false {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+func TestCreateError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `[{"message":"Required fields are missing: [LastName]","errorCode":"REQUIRED_FIELD_MISSING","fields":["LastName"]}]`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	_, err := f.Create(Contact{FirstName: "Jake"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	t.Log(err)
+}
+
+func TestGetUpdateDelete(t *testing.T) {
+	type Invoice struct {
+		Id     string
+		Amount float64
+		Paid   bool
+		Notes  string
+	}
+
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/data/v27.0/sobjects/Invoice__c/a01" {
+			t.Errorf("unexpected path %v", r.URL.Path)
+		}
+		switch r.Method {
+		case "GET":
+			if r.URL.Query().Get("fields") != "Id,Amount" {
+				t.Errorf("unexpected fields %q", r.URL.Query().Get("fields"))
+			}
+			fmt.Fprint(w, `{"attributes":{"type":"Invoice__c"},"Id":"a01","Amount":42}`)
+		case "PATCH":
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &body)
+			w.WriteHeader(http.StatusNoContent)
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	var inv Invoice
+	if err := f.Get("Invoice__c", "a01", &inv, "Id", "Amount"); err != nil {
+		t.Fatal(err)
+	}
+	if inv.Id != "a01" || inv.Amount != 42 {
+		t.Errorf("unexpected record %v", inv)
+	}
+
+	if err := f.Update("Invoice__c", "a01", Invoice{Amount: 50}, "Paid"); err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 2 || body["Amount"] != 50.0 || body["Paid"] != false {
+		t.Errorf("unexpected update body %v", body)
+	}
+
+	if err := f.Delete("Invoice__c", "a01"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDateFields(t *testing.T) {
+	type Opportunity struct {
+		Id        string
+		CloseDate time.Time            `force:",date"`
+		Birthdate *time.Time           `force:",date"`
+		Renewal   simpleforce.NullTime `force:",date"`
+		Closed    time.Time
+	}
+
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &body)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, `{"Id":"006","CloseDate":"2013-05-01","Birthdate":"1980-02-29","Renewal":"2014-01-01",
+			"Closed":"2013-05-01T16:30:00.000+0000"}`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	var o Opportunity
+	if err := f.Get("Opportunity", "006", &o); err != nil {
+		t.Fatal(err)
+	}
+	o.CloseDate = o.CloseDate.AddDate(0, 0, 1)
+	if err := f.Update("Opportunity", o.Id, o); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"CloseDate": "2013-05-02",
+		"Birthdate": "1980-02-29",
+		"Renewal":   "2014-01-01",
+		"Closed":    "2013-05-01T16:30:00Z",
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("expected %v, got %v", want, body)
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	var c Contact
+	if err := f.Get("Contact", "003", &c); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	type Account struct {
+		Name      string
+		ERP_Id__c string
+		Employees int
+	}
+
+	var body map[string]interface{}
+	exists := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/services/data/v27.0/sobjects/Account/ERP_Id__c/ERP 7" {
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		if exists {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		exists = true
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"001000000000001","success":true,"errors":[]}`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	a := Account{Name: "Mutual Mobile", ERP_Id__c: "ERP 7"}
+	created, err := f.Upsert("Account", "ERP_Id__c", a.ERP_Id__c, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("expected the first upsert to create")
+	}
+	if _, ok := body["ERP_Id__c"]; ok {
+		t.Error("external id field should not be in the body")
+	}
+	if body["Name"] != "Mutual Mobile" {
+		t.Errorf("unexpected body %v", body)
+	}
+	created, err = f.Upsert("Account", "ERP_Id__c", a.ERP_Id__c, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Error("expected the second upsert to update")
+	}
+}
+
+func TestQueryFollowsNextRecordsUrl(t *testing.T) {
+	type Contact struct {
+		Name string
+	}
+	type Account struct {
+		Name     string
+		Contacts []Contact
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/data/v27.0/query":
+			fmt.Fprint(w, `{"totalSize":3,"done":false,"nextRecordsUrl":"/services/data/v27.0/query/01g-2000","records":[
+				{"Name":"A","Contacts":{"totalSize":2,"done":false,"nextRecordsUrl":"/services/data/v27.0/query/01g-child","records":[{"Name":"A1"}]}},
+				{"Name":"B","Contacts":null}]}`)
+		case "/services/data/v27.0/query/01g-2000":
+			fmt.Fprint(w, `{"totalSize":3,"done":true,"records":[{"Name":"C"}]}`)
+		case "/services/data/v27.0/query/01g-child":
+			fmt.Fprint(w, `{"totalSize":2,"done":true,"records":[{"Name":"A2"}]}`)
+		default:
+			t.Errorf("unexpected path %v", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL+"/services/data/v27.0")
+	var as []Account
+	if err := f.Query("SELECT Name, (SELECT Name FROM Contacts) FROM Account", &as); err != nil {
+		t.Fatal(err)
+	}
+	if len(as) != 3 || as[0].Name != "A" || as[1].Name != "B" || as[2].Name != "C" {
+		t.Fatalf("unexpected accounts %v", as)
+	}
+	if len(as[0].Contacts) != 2 || as[0].Contacts[1].Name != "A2" {
+		t.Errorf("unexpected contacts %v", as[0].Contacts)
+	}
+	if as[1].Contacts != nil {
+		t.Errorf("expected no contacts, got %v", as[1].Contacts)
+	}
+}
+
+func TestQueryIter(t *testing.T) {
+	type Task struct {
+		Subject string
+	}
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/services/data/v27.0/query":
+			fmt.Fprint(w, `{"totalSize":3,"done":false,"nextRecordsUrl":"/services/data/v27.0/query/01g-2","records":[{"Subject":"a"},{"Subject":"b"}]}`)
+		case "/services/data/v27.0/query/01g-2":
+			fmt.Fprint(w, `{"totalSize":3,"done":false,"nextRecordsUrl":"/services/data/v27.0/query/01g-3","records":[{"Subject":"c"}]}`)
+		case "/services/data/v27.0/query/01g-3":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `[{"errorCode":"INVALID_QUERY_LOCATOR","message":"invalid query locator"}]`)
+		}
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	it := f.QueryIter("SELECT Subject FROM Task")
+	var task Task
+	var subjects []string
+	for it.Next(&task) {
+		subjects = append(subjects, task.Subject)
+	}
+	if len(subjects) != 3 || subjects[2] != "c" {
+		t.Errorf("unexpected subjects %v", subjects)
+	}
+	if it.TotalSize() != 3 {
+		t.Errorf("unexpected total size %v", it.TotalSize())
+	}
+	if it.Err() == nil {
+		t.Error("expected the failed batch to surface an error")
+	}
+
+	requests = 0
+	it = f.QueryIter("SELECT Subject FROM Task")
+	for it.Next(&task) {
+		break
+	}
+	if requests != 1 || it.Err() != nil {
+		t.Errorf("stopping early should fetch one batch, fetched %v, err %v", requests, it.Err())
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/data/v27.0/query":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `[{"message":"No such column 'Nope' on entity 'Contact'","errorCode":"INVALID_FIELD"}]`)
+		case "/services/data/v27.0/sobjects/Contact/003":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`)
+		}
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	var cs []Contact
+	err := f.Query("SELECT Nope FROM Contact", &cs)
+	apiErr, ok := err.(*simpleforce.APIError)
+	if !ok {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.ErrorCode != "INVALID_FIELD" {
+		t.Errorf("unexpected error %v", apiErr)
+	}
+	if len(cs) != 0 {
+		t.Errorf("expected no results, got %v", cs)
+	}
+
+	var c Contact
+	err = f.Get("Contact", "003", &c)
+	if !simpleforce.IsNotFound(err) || simpleforce.IsSessionExpired(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	err = f.Delete("Contact", "004")
+	if !simpleforce.IsSessionExpired(err) || simpleforce.IsNotFound(err) {
+		t.Errorf("expected session expired, got %v", err)
+	}
+}
+
+func TestLoginError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"invalid_grant","error_description":"authentication failure"}`)
+	}))
+	defer srv.Close()
+
+	_, err := simpleforce.NewWithCredentials(srv.URL, "key", "secret", "user", "pass")
+	apiErr, ok := err.(*simpleforce.APIError)
+	if !ok || apiErr.ErrorCode != "invalid_grant" {
+		t.Errorf("expected invalid_grant, got %v", err)
+	}
+}
+
+func TestSessionRenewal(t *testing.T) {
+	var mu sync.Mutex
+	logins := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/services/oauth2/token" {
+			logins++
+			fmt.Fprintf(w, `{"access_token":"session%v","instance_url":"http://%v"}`, logins, r.Host)
+			return
+		}
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer session%v", logins) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`)
+			return
+		}
+		fmt.Fprint(w, `{"totalSize":1,"done":true,"records":[{"Name":"Jake"}]}`)
+	}))
+	defer srv.Close()
+
+	f, err := simpleforce.NewWithCredentials(srv.URL, "key", "secret", "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Expire the session out from under every goroutine at once.
+	mu.Lock()
+	logins++
+	mu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var cs []Contact
+			if err := f.Query("SELECT Name FROM Contact", &cs); err != nil {
+				errs <- err
+			} else if len(cs) != 1 {
+				errs <- fmt.Errorf("unexpected results %v", cs)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if logins != 3 {
+		t.Errorf("expected a single renewal, got %v logins", logins-1)
+	}
+}
+
+func TestSessionRenewalHonorsContext(t *testing.T) {
+	var mu sync.Mutex
+	logins := 0
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/oauth2/token" {
+			mu.Lock()
+			logins++
+			n := logins
+			mu.Unlock()
+			if n > 1 {
+				// Hold the renewal until the test lets it go.
+				<-release
+			}
+			fmt.Fprintf(w, `{"access_token":"session%v","instance_url":"http://%v"}`, n, r.Host)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer session2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`)
+			return
+		}
+		fmt.Fprint(w, `{"totalSize":1,"done":true,"records":[{"Name":"Jake"}]}`)
+	}))
+	defer srv.Close()
+
+	f, err := simpleforce.NewWithCredentials(srv.URL, "key", "secret", "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := make(chan error, 1)
+	go func() {
+		var cs []Contact
+		first <- f.Query("SELECT Name FROM Contact", &cs)
+	}()
+	for {
+		mu.Lock()
+		n := logins
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// While the renewal is stuck, a request with its own deadline still gives up on time.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var cs []Contact
+	start := time.Now()
+	if err := f.QueryContext(ctx, "SELECT Name FROM Contact", &cs); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("waited %v for a renewal past the deadline", time.Since(start))
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Error(err)
+	}
+	if logins != 2 {
+		t.Errorf("expected a single renewal, got %v logins", logins-1)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/data":
+			fmt.Fprint(w, `[{"label":"Winter '13","url":"/services/data/v26.0","version":"26.0"},
+				{"label":"Summer '23","url":"/services/data/v58.0","version":"58.0"},
+				{"label":"Spring '13","url":"/services/data/v27.0","version":"27.0"}]`)
+		case "/services/data/v58.0/query", "/services/data/v31.0/query":
+			fmt.Fprint(w, `{"totalSize":0,"done":true,"records":[]}`)
+		default:
+			t.Errorf("unexpected path %v", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	if f.Version() != simpleforce.DefaultVersion {
+		t.Errorf("expected the default version, got %v", f.Version())
+	}
+	if v := simpleforce.New("session", srv.URL+"/services/data/v31.0").Version(); v != "31.0" {
+		t.Errorf("expected the version from the url, got %v", v)
+	}
+	versions, err := f.Versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].Version != "26.0" || versions[1].Version != "27.0" || versions[2].Version != "58.0" {
+		t.Errorf("unexpected versions %v", versions)
+	}
+	latest, err := f.UseLatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version() != "58.0" || f.Version() != simpleforce.DefaultVersion {
+		t.Errorf("unexpected versions %v and %v", latest.Version(), f.Version())
+	}
+	var cs []Contact
+	if err := latest.Query("SELECT Name FROM Contact", &cs); err != nil {
+		t.Error(err)
+	}
+	if err := simpleforce.New("session", srv.URL, simpleforce.WithVersion("31.0")).Query("SELECT Name FROM Contact", &cs); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "sync/1.0" {
+			t.Errorf("unexpected user agent %q on %v", r.Header.Get("User-Agent"), r.URL.Path)
+		}
+		if r.URL.Path == "/services/oauth2/token" {
+			fmt.Fprintf(w, `{"access_token":"session","instance_url":"http://%v"}`, r.Host)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer session" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path == "/services/data/v27.0/query" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		fmt.Fprint(w, `{"totalSize":0,"done":true,"records":[]}`)
+	}))
+	defer srv.Close()
+
+	var order []string
+	trace := func(name string) simpleforce.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripper(func(r *http.Request) (*http.Response, error) {
+				order = append(order, name+" "+r.URL.Path)
+				return next.RoundTrip(r)
+			})
+		}
+	}
+	client := &http.Client{}
+	f, err := simpleforce.NewWithCredentials(srv.URL, "key", "secret", "user", "pass",
+		simpleforce.WithHTTPClient(client),
+		simpleforce.WithUserAgent("sync/1.0"),
+		simpleforce.WithMiddleware(trace("outer"), trace("inner")),
+		simpleforce.WithTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Timeout != 0 {
+		t.Error("WithTimeout should not change the given client")
+	}
+	var c Contact
+	if err := f.Get("Contact", "003", &c); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"outer /services/oauth2/token", "inner /services/oauth2/token",
+		"outer /services/data/v27.0/sobjects/Contact/003", "inner /services/data/v27.0/sobjects/Contact/003",
+	}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("unexpected middleware calls %v", order)
+	}
+	var cs []Contact
+	if err := f.Query("SELECT Name FROM Contact", &cs); err == nil {
+		t.Error("expected the slow query to time out")
+	}
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (fn roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func TestTimeoutBeforeHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, `{"totalSize":0,"done":true,"records":[]}`)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{}
+	f := simpleforce.New("session", srv.URL,
+		simpleforce.WithTimeout(20*time.Millisecond),
+		simpleforce.WithHTTPClient(client))
+	var cs []Contact
+	if err := f.Query("SELECT Name FROM Contact", &cs); err == nil {
+		t.Error("expected the slow query to time out")
+	}
+	if client.Timeout != 0 {
+		t.Error("WithTimeout should not change the given client")
+	}
+}
+
+func TestQueryContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	f := simpleforce.New("session", srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var cs []Contact
+	err := f.QueryContext(ctx, "SELECT Name FROM Contact", &cs)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := f.DeleteContext(ctx, "Contact", "003"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the request to be canceled, got %v", err)
+	}
+}
+
+func TestAPIUsageAndLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/data/v29.0/limits":
+			w.Header().Set("Sforce-Limit-Info", "api-usage=19/5000")
+			fmt.Fprint(w, `{"DailyApiRequests":{"Max":5000,"Remaining":4981},"DataStorageMB":{"Max":5,"Remaining":4},
+				"DailyBulkApiRequests":{"Max":5000,"Remaining":5000},"SomethingNew":{"Max":3,"Remaining":1}}`)
+		default:
+			w.Header().Set("Sforce-Limit-Info", "api-usage=18/5000")
+			fmt.Fprint(w, `{"totalSize":0,"done":true,"records":[]}`)
+		}
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL, simpleforce.WithVersion("29.0"))
+	if f.APIUsage() != (simpleforce.APIUsage{}) {
+		t.Errorf("expected no usage before any request, got %v", f.APIUsage())
+	}
+	var cs []Contact
+	if err := f.Query("SELECT Name FROM Contact", &cs); err != nil {
+		t.Fatal(err)
+	}
+	if f.APIUsage() != (simpleforce.APIUsage{Used: 18, Max: 5000}) {
+		t.Errorf("unexpected usage %v", f.APIUsage())
+	}
+	limits, err := f.Limits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.DailyApiRequests != (simpleforce.Limit{Max: 5000, Remaining: 4981}) || limits.DataStorageMB.Remaining != 4 {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+	if limits.All["SomethingNew"].Max != 3 {
+		t.Errorf("expected unknown limits to be kept, got %v", limits.All)
+	}
+	if f.APIUsage().Used != 19 {
+		t.Errorf("expected the usage to follow the latest response, got %v", f.APIUsage())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL, simpleforce.WithRateLimit(simpleforce.RateLimit{
+		PerSecond:   100,
+		Burst:       5,
+		MaxInFlight: 2,
+		DailyBudget: 10,
+	}))
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.Delete("Contact", "003"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// Five calls come out of the burst, the other five wait 10ms apiece for tokens.
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("expected the calls to be spread out, took %v", elapsed)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("expected at most two calls in flight, saw %v", maxInFlight)
+	}
+	if err := f.Delete("Contact", "003"); err != simpleforce.ErrDailyBudgetExhausted {
+		t.Errorf("expected the budget to be exhausted, got %v", err)
+	}
+}
+
+func TestRateLimitBudgetFailsFast(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL, simpleforce.WithRateLimit(simpleforce.RateLimit{
+		PerSecond:   1,
+		Burst:       1,
+		DailyBudget: 1,
+	}))
+	if err := f.Delete("Contact", "003"); err != nil {
+		t.Fatal(err)
+	}
+	// The bucket is empty for the next second, but a call over budget shouldn't wait on it.
+	start := time.Now()
+	if err := f.Delete("Contact", "003"); err != simpleforce.ErrDailyBudgetExhausted {
+		t.Errorf("expected the budget to be exhausted, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected to fail fast, took %v", elapsed)
+	}
+}
+
+func TestRateLimitContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL, simpleforce.WithRateLimit(simpleforce.RateLimit{PerSecond: 0.001}))
+	if err := f.Delete("Contact", "003"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := f.DeleteContext(ctx, "Contact", "003"); err != context.DeadlineExceeded {
+		t.Errorf("expected to give up waiting for a token, got %v", err)
+	}
+
+	// a call that gives up waiting for an in-flight slot hands its token back.
+	started, unblock := make(chan struct{}), make(chan struct{})
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/blocked") {
+			close(started)
+			<-unblock
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer blocking.Close()
+	f = simpleforce.New("session", blocking.URL, simpleforce.WithRateLimit(simpleforce.RateLimit{PerSecond: 0.001, Burst: 2, MaxInFlight: 1}))
+	done := make(chan error)
+	go func() {
+		done <- f.Delete("Contact", "blocked")
+	}()
+	<-started
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := f.DeleteContext(ctx, "Contact", "003"); err != context.DeadlineExceeded {
+		t.Errorf("expected to give up waiting for a slot, got %v", err)
+	}
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := f.DeleteContext(ctx, "Contact", "003"); err != nil {
+		t.Errorf("expected the refunded token to be used, got %v", err)
+	}
+}
+
+func TestFieldTags(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	type Account struct {
+		Id      string
+		Revenue float64 `force:"Annual_Revenue__c"`
+		Total   float64 `force:",readonly"`
+		Notes   string  `force:",omitempty"`
+		Cache   string  `force:"-"`
+		Owner   *User   `force:"Owner__r"`
+	}
+
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &body)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"001","success":true,"errors":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"totalSize":1,"done":true,"records":[{"Id":"001","Annual_Revenue__c":5.5,"Total":9,"Cache":"nope","Owner__r":{"Name":"Jake"}}]}`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	var as []Account
+	if err := f.Query("SELECT Id, Annual_Revenue__c, Total, Owner__r.Name FROM Account", &as); err != nil {
+		t.Fatal(err)
+	}
+	if len(as) != 1 || as[0].Revenue != 5.5 || as[0].Total != 9 || as[0].Cache != "" || as[0].Owner.Name != "Jake" {
+		t.Errorf("unexpected accounts %+v", as)
+	}
+	if _, err := f.Create(as[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 1 || body["Annual_Revenue__c"] != 5.5 {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+func TestNullableAndNumericFields(t *testing.T) {
+	type Record struct {
+		Id        string
+		Small     int8
+		Count     int32
+		Big       uint64
+		Employees uint16
+		Ratio     float32
+		Title     *string
+		Phone     *string
+		Amount    *float64
+		Closed    *time.Time
+		Region    simpleforce.NullString
+		Score     simpleforce.NullFloat
+		Renewal   simpleforce.NullTime
+		Expires   simpleforce.NullTime
+		Birthdate time.Time
+		Active    *bool
+	}
+
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &body)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, `{"totalSize":1,"done":true,"records":[{"Id":"001","Small":-5,"Count":70000,"Big":9007199254740993,
+			"Employees":25.0,"Ratio":0.5,"Title":"CEO","Phone":null,"Amount":0,"Closed":"2013-05-01T16:30:00.000+0000",
+			"Region":"EMEA","Score":null,"Renewal":"2014-01-01","Expires":null,"Birthdate":null,"Active":false}]}`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	var rs []Record
+	if err := f.Query("SELECT Id FROM Record__c", &rs); err != nil {
+		t.Fatal(err)
+	}
+	r := rs[0]
+	if r.Small != -5 || r.Count != 70000 || r.Big != 9007199254740993 || r.Employees != 25 || r.Ratio != 0.5 {
+		t.Errorf("unexpected numbers %+v", r)
+	}
+	if r.Title == nil || *r.Title != "CEO" || r.Phone != nil || r.Amount == nil || *r.Amount != 0 || r.Active == nil || *r.Active {
+		t.Errorf("unexpected pointers %+v", r)
+	}
+	if r.Closed == nil || !r.Closed.Equal(time.Date(2013, 5, 1, 16, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected datetime %v", r.Closed)
+	}
+	if r.Region != (simpleforce.NullString{String: "EMEA", Valid: true}) || r.Score.Valid || !r.Renewal.Valid || r.Expires.Valid {
+		t.Errorf("unexpected null types %+v", r)
+	}
+	if !r.Birthdate.IsZero() {
+		t.Errorf("expected NULL to leave a zero time, got %v", r.Birthdate)
+	}
+
+	if err := f.Update("Record__c", r.Id, r, "Phone", "Score"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := body["Phone"]; !ok || v != nil {
+		t.Errorf("expected a named nil pointer to be sent as NULL, got %v", body)
+	}
+	if v, ok := body["Score"]; !ok || v != nil {
+		t.Errorf("expected a named invalid NullFloat to be sent as NULL, got %v", body)
+	}
+	if _, ok := body["Expires"]; ok {
+		t.Errorf("expected an unnamed invalid NullTime to be left out, got %v", body)
+	}
+	if body["Region"] != "EMEA" || body["Amount"] != 0.0 || body["Active"] != false || body["Small"] != -5.0 {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+// Amounts in cents, to avoid floating point rounding.
+type Cents int64
+
+func (c *Cents) UnmarshalForce(data []byte) error {
+	if string(data) == "null" {
+		*c = -1
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	*c = Cents(f*100 + 0.5)
+	return nil
+}
+
+func (c Cents) MarshalForce() ([]byte, error) {
+	if c < 0 {
+		return []byte("null"), nil
+	}
+	return json.Marshal(float64(c) / 100)
+}
+
+type Stage struct {
+	Name string
+}
+
+func (s *Stage) UnmarshalForce(data []byte) error {
+	return json.Unmarshal(data, &s.Name)
+}
+
+func (s *Stage) MarshalForce() ([]byte, error) {
+	if s.Name == "" {
+		return nil, fmt.Errorf("empty stage")
+	}
+	return json.Marshal(s.Name)
+}
+
+func TestMarshalerAndUnmarshaler(t *testing.T) {
+	type Opportunity struct {
+		Amount   Cents
+		Discount Cents
+		Fee      *Cents
+		Stage    Stage `force:"StageName"`
+	}
+
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &body)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"006","success":true,"errors":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"totalSize":1,"done":true,"records":[{"Amount":12.34,"Discount":null,"Fee":1.5,"StageName":"Closed Won"}]}`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	var opps []Opportunity
+	if err := f.Query("SELECT Amount, Discount, Fee, StageName FROM Opportunity", &opps); err != nil {
+		t.Fatal(err)
+	}
+	o := opps[0]
+	if o.Amount != 1234 || o.Discount != -1 || o.Fee == nil || *o.Fee != 150 || o.Stage.Name != "Closed Won" {
+		t.Errorf("unexpected opportunity %+v", o)
+	}
+	if _, err := f.Create(o); err != nil {
+		t.Fatal(err)
+	}
+	if body["Amount"] != 12.34 || body["Discount"] != nil || body["Fee"] != 1.5 || body["StageName"] != "Closed Won" {
+		t.Errorf("unexpected body %v", body)
+	}
+	if _, err := f.Create(Opportunity{}); err == nil {
+		t.Error("expected the marshaler's error")
+	}
+}
+
+func TestQueryDecodesEscapesAndMismatches(t *testing.T) {
+	type Record struct {
+		Name   string
+		Count  int
+		Amount float64
+		Active bool
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"totalSize":2,"done":true,"records":[
+			{"Name":"O\"Brien é\\n","Count":3,"Amount":"12.5","Active":"true"},
+			{"Name":7,"Count":1,"Amount":2.5,"Active":true,"Extra":{"nested":[1,2]}}]}`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	rs := make([]Record, 0, 4)
+	stale := rs[:2]
+	stale[0], stale[1] = Record{Name: "stale", Count: 9}, Record{Name: "stale", Count: 9}
+	if err := f.Query("SELECT Name FROM Record__c", &rs); err != nil {
+		t.Fatal(err)
+	}
+	want := []Record{{`O"Brien é\n`, 3, 0, false}, {"", 1, 2.5, true}}
+	if !reflect.DeepEqual(rs, want) {
+		t.Errorf("expected %+v, got %+v", want, rs)
+	}
+}
+
+func TestRecordsAndMaps(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"totalSize":1,"done":true,"records":[{
+			"attributes":{"type":"Account","url":"/services/data/v27.0/sobjects/Account/001"},
+			"Name":"Mutual Mobile","NumberOfEmployees":250,"IsPartner":true,"CreatedDate":"2013-05-01T16:30:00.000+0000",
+			"Owner":{"attributes":{"type":"User"},"Name":"Jake","Manager":null},
+			"BillingAddress":{"city":"Austin"},
+			"Contacts":{"totalSize":2,"done":true,"records":[
+				{"attributes":{"type":"Contact"},"Name":"Ann"},{"attributes":{"type":"Contact"},"Name":"Bob"}]}}]}`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	var rs []simpleforce.Record
+	if err := f.Query("SELECT Name FROM Account", &rs); err != nil {
+		t.Fatal(err)
+	}
+	r := rs[0]
+	if r.Attributes.Type != "Account" || r.Attributes.Url != "/services/data/v27.0/sobjects/Account/001" {
+		t.Errorf("unexpected attributes %+v", r.Attributes)
+	}
+	if r.GetString("Name") != "Mutual Mobile" || r.GetString("name") != "Mutual Mobile" || r.GetInt("NumberOfEmployees") != 250 || !r.GetBool("IsPartner") {
+		t.Errorf("unexpected fields %+v", r.Fields)
+	}
+	if !r.GetTime("CreatedDate").Equal(time.Date(2013, 5, 1, 16, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time %v", r.GetTime("CreatedDate"))
+	}
+	if r.GetString("Owner.Name") != "Jake" || r.GetRecord("Owner").Attributes.Type != "User" {
+		t.Errorf("unexpected parent %+v", r.GetRecord("Owner"))
+	}
+	if r.GetString("Owner.Manager.Name") != "" || r.GetRecord("Owner.Manager") != nil {
+		t.Error("expected a NULL parent to read as nil")
+	}
+	if _, ok := r.Get("Missing.Name"); ok {
+		t.Error("expected a missing path")
+	}
+	if addr, _ := r.Get("BillingAddress"); !reflect.DeepEqual(addr, map[string]interface{}{"city": "Austin"}) {
+		t.Errorf("unexpected compound field %v", addr)
+	}
+	if cs := r.GetRecords("Contacts"); len(cs) != 2 || cs[1].GetString("Name") != "Bob" {
+		t.Errorf("unexpected children %+v", cs)
+	}
+
+	var ms []map[string]interface{}
+	if err := f.Query("SELECT Name FROM Account", &ms); err != nil {
+		t.Fatal(err)
+	}
+	m := ms[0]
+	if m["Name"] != "Mutual Mobile" || m["NumberOfEmployees"] != 250.0 || m["Owner"].(map[string]interface{})["Name"] != "Jake" {
+		t.Errorf("unexpected map %v", m)
+	}
+	if cs, ok := m["Contacts"].([]map[string]interface{}); !ok || len(cs) != 2 || cs[0]["Name"] != "Ann" {
+		t.Errorf("unexpected children %v", m["Contacts"])
+	}
+
+	it := f.QueryIter("SELECT Name FROM Account")
+	var ir simpleforce.Record
+	if !it.Next(&ir) || ir.GetString("Owner.Name") != "Jake" {
+		t.Errorf("unexpected record from Next %+v, %v", ir, it.Err())
+	}
+
+	var ints []int
+	if err := f.Query("SELECT Name FROM Account", &ints); err == nil {
+		t.Error("expected an error for a slice of ints")
+	}
+}
+
+func TestCircularDecoding(t *testing.T) {
+	type User struct {
+		Name    string
+		Manager *User
+	}
+	type Contact struct {
+		Name  string
+		Owner *User
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"totalSize":2,"done":true,"records":[
+			{"Name":"Ann","Owner":{"Name":"Jake","Manager":{"Name":"Kyle","Manager":null}}},
+			{"Name":"Bob"}]}`)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	var cs []Contact
+	if err := f.Query("SELECT Name, Owner.Name, Owner.Manager.Name FROM Contact", &cs); err != nil {
+		t.Fatal(err)
+	}
+	if cs[0].Owner.Manager.Name != "Kyle" || cs[0].Owner.Manager.Manager != nil {
+		t.Errorf("unexpected managers %+v", cs[0].Owner.Manager)
+	}
+	if cs[1].Owner == nil || cs[1].Owner.Manager != nil {
+		t.Errorf("expected a missing owner to be allocated once, got %+v", cs[1].Owner)
+	}
+}
+
+// Serves a single 1000 record batch of contacts, so the decoder can be measured without an org.
+func BenchmarkQueryDecode(b *testing.B) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"totalSize":1000,"done":true,"records":[`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, `{"attributes":{"type":"Contact","url":"/services/data/v27.0/sobjects/Contact/003%012d"},
+			"FirstName":"Jake","LastName":"Basile %d","Name":"Jake Basile %d","Account":{"attributes":{"type":"Account"},"Name":"Mutual Mobile"}}`, i, i, i)
+	}
+	buf.WriteString(`]}`)
+	page := buf.Bytes()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(page)
+	}))
+	defer srv.Close()
+
+	f := simpleforce.New("session", srv.URL)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var cs []Contact
+		if err := f.Query("SELECT Account.Name, FirstName, LastName, Name FROM Contact", &cs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/jakebasile/simpleforce
+
+go 1.21
@@ -0,0 +1,112 @@
+package simpleforce
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// How much of the org's daily API allocation has been used, as of the most recent response.
+type APIUsage struct {
+	Used int
+	Max  int
+}
+
+// One limit from the /limits resource.
+type Limit struct {
+	Max       int
+	Remaining int
+}
+
+// The org limits reported by the /limits resource. The common ones have their own fields; All holds
+// every limit the instance reported, keyed by name, including any added in later API versions.
+type Limits struct {
+	DailyApiRequests                  Limit
+	DailyBulkApiRequests              Limit
+	DailyAsyncApexExecutions          Limit
+	DailyStreamingApiEvents           Limit
+	DataStorageMB                     Limit
+	FileStorageMB                     Limit
+	ConcurrentAsyncGetReportInstances Limit
+	ConcurrentSyncReportRuns          Limit
+	HourlyAsyncReportRuns             Limit
+	HourlySyncReportRuns              Limit
+	MassEmail                         Limit
+	SingleEmail                       Limit
+	All                               map[string]Limit
+}
+
+// Returns the API usage reported in the Sforce-Limit-Info header of the most recent response to any
+// copy of this Force. It is zero until a response has carried the header.
+func (f Force) APIUsage() APIUsage {
+	if f.usage == nil {
+		return APIUsage{}
+	}
+	f.usage.mu.Lock()
+	defer f.usage.mu.Unlock()
+	return f.usage.latest
+}
+
+// Fetches the org's limits. The /limits resource needs API version 29.0 or later; see WithVersion.
+func (f Force) Limits() (Limits, error) {
+	return f.LimitsContext(context.Background())
+}
+
+// Like Limits, but the request is bound to the given context.
+func (f Force) LimitsContext(ctx context.Context) (Limits, error) {
+	all := make(map[string]Limit)
+	if _, err := f.sendJson(ctx, "GET", f.dataUrl()+"/limits", nil, &all); err != nil {
+		return Limits{}, err
+	}
+	return Limits{
+		DailyApiRequests:                  all["DailyApiRequests"],
+		DailyBulkApiRequests:              all["DailyBulkApiRequests"],
+		DailyAsyncApexExecutions:          all["DailyAsyncApexExecutions"],
+		DailyStreamingApiEvents:           all["DailyStreamingApiEvents"],
+		DataStorageMB:                     all["DataStorageMB"],
+		FileStorageMB:                     all["FileStorageMB"],
+		ConcurrentAsyncGetReportInstances: all["ConcurrentAsyncGetReportInstances"],
+		ConcurrentSyncReportRuns:          all["ConcurrentSyncReportRuns"],
+		HourlyAsyncReportRuns:             all["HourlyAsyncReportRuns"],
+		HourlySyncReportRuns:              all["HourlySyncReportRuns"],
+		MassEmail:                         all["MassEmail"],
+		SingleEmail:                       all["SingleEmail"],
+		All:                               all,
+	}, nil
+}
+
+// The latest API usage seen, shared by every copy of a Force.
+type usageState struct {
+	mu     sync.Mutex
+	latest APIUsage
+}
+
+// Records the usage from a Sforce-Limit-Info header, such as "api-usage=18/5000". Headers without
+// usage information are ignored.
+func (u *usageState) record(header string) {
+	if u == nil {
+		return
+	}
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "api-usage=") {
+			continue
+		}
+		nums := strings.SplitN(strings.TrimPrefix(part, "api-usage="), "/", 2)
+		if len(nums) != 2 {
+			continue
+		}
+		used, err := strconv.Atoi(nums[0])
+		if err != nil {
+			continue
+		}
+		max, err := strconv.Atoi(nums[1])
+		if err != nil {
+			continue
+		}
+		u.mu.Lock()
+		u.latest = APIUsage{used, max}
+		u.mu.Unlock()
+	}
+}
@@ -0,0 +1,41 @@
+package simpleforce
+
+import (
+	"reflect"
+	"time"
+)
+
+// A string field that may be NULL. Valid is false for NULL. Pointer fields such as *string work too,
+// with nil meaning NULL.
+type NullString struct {
+	String string
+	Valid  bool
+}
+
+// A number field that may be NULL. Valid is false for NULL.
+type NullFloat struct {
+	Float float64
+	Valid bool
+}
+
+// A date or datetime field that may be NULL. Valid is false for NULL.
+type NullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	nullStringType = reflect.TypeOf(NullString{})
+	nullFloatType  = reflect.TypeOf(NullFloat{})
+	nullTimeType   = reflect.TypeOf(NullTime{})
+)
+
+// Reports whether values of the given struct type are single field values rather than records.
+func isValueStruct(t reflect.Type) bool {
+	switch t {
+	case timeType, nullStringType, nullFloatType, nullTimeType:
+		return true
+	}
+	return false
+}
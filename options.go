@@ -0,0 +1,75 @@
+package simpleforce
+
+import (
+	"net/http"
+	"time"
+)
+
+// Wraps the transport every request goes through, to inspect or change requests and responses.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// Sends requests with the given client instead of http.DefaultClient. The client is copied once every
+// option has been applied, so options that change it, such as WithTimeout, leave the original alone
+// whatever order they are given in.
+func WithHTTPClient(client *http.Client) Option {
+	return func(f *Force) {
+		if client == nil {
+			client = http.DefaultClient
+		}
+		f.client = client
+	}
+}
+
+// Gives up on any request, including logging in, that takes longer than the given duration.
+func WithTimeout(timeout time.Duration) Option {
+	return func(f *Force) {
+		f.timeout = timeout
+	}
+}
+
+// Sends the given User-Agent header with every request.
+func WithUserAgent(userAgent string) Option {
+	return WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r = r.Clone(r.Context())
+			r.Header.Set("User-Agent", userAgent)
+			return next.RoundTrip(r)
+		})
+	})
+}
+
+// Adds middleware to the transport every request goes through. Middleware given earlier sees requests
+// first and responses last.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(f *Force) {
+		f.middleware = append(f.middleware, middleware...)
+	}
+}
+
+// Returns a copy of the configured client with the configured timeout and its transport wrapped in
+// the configured middleware.
+func (f Force) wrapClient() *http.Client {
+	c := *f.client
+	if f.timeout > 0 {
+		c.Timeout = f.timeout
+	}
+	if len(f.middleware) == 0 {
+		return &c
+	}
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(f.middleware) - 1; i >= 0; i-- {
+		transport = f.middleware[i](transport)
+	}
+	c.Transport = transport
+	return &c
+}
+
+// Lets an ordinary function act as an http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
@@ -0,0 +1,159 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"github.com/jakebasile/simpleforce"
+	"reflect"
+	"strings"
+)
+
+// Creates a COUNT expression, which counts the records with a non-NULL value for the field.
+func Count(field string) string {
+	return "COUNT(" + field + ")"
+}
+
+// Creates a COUNT_DISTINCT expression, which counts the distinct non-NULL values of the field.
+func CountDistinct(field string) string {
+	return "COUNT_DISTINCT(" + field + ")"
+}
+
+// Creates a SUM expression.
+func Sum(field string) string {
+	return "SUM(" + field + ")"
+}
+
+// Creates an AVG expression.
+func Avg(field string) string {
+	return "AVG(" + field + ")"
+}
+
+// Creates a MIN expression.
+func Min(field string) string {
+	return "MIN(" + field + ")"
+}
+
+// Creates a MAX expression.
+func Max(field string) string {
+	return "MAX(" + field + ")"
+}
+
+// Creates a GROUPING expression, which is 1 on the subtotal rows GroupByRollup and GroupByCube add
+// for the field, and 0 otherwise.
+func Grouping(field string) string {
+	return "GROUPING(" + field + ")"
+}
+
+// Selects an expression, such as Sum("Amount") or a grouped field like "Owner.Name", in place of the
+// given field of the destination struct. The expression is aliased with the field's API name, so
+// aggregate results decode into the field:
+//
+//	type StageTotal struct {
+//		_         struct{} `sobject:"Opportunity"`
+//		StageName string
+//		Total     float64 `force:"total"`
+//	}
+//	q.Alias("total", query.Sum("Amount"))
+//	q.GroupBy("StageName")
+//
+// Returns an error if the struct has no such field, or the field is a relationship.
+func (q *Query) Alias(field, expr string) error {
+	t := reflect.TypeOf(q.dest).Elem().Elem()
+	if strings.Contains(field, ".") || !hasField(t, field) {
+		return fmt.Errorf("query: cannot alias %v, no such field", field)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		info, ok := simpleforce.ParseField(t.Field(i))
+		if ok && strings.EqualFold(info.Name, field) {
+			field = info.Name
+		}
+	}
+	if q.aliases == nil {
+		q.aliases = make(map[string]string)
+	}
+	q.aliases[field] = expr
+	return nil
+}
+
+// Groups the results by the given fields, which may be fields of the destination struct or field
+// paths selected with Alias. A struct field aliased to a field path, such as "Owner.Name", is grouped
+// by that path instead. Returns an error for fields aliased to an aggregate, and for any other field.
+func (q *Query) GroupBy(fields ...string) error {
+	return q.group("", fields)
+}
+
+// Like GroupBy, but adds subtotal rows for each level of grouping and a grand total row.
+func (q *Query) GroupByRollup(fields ...string) error {
+	return q.group("ROLLUP", fields)
+}
+
+// Like GroupBy, but adds subtotal rows for every combination of the grouped fields and a grand total
+// row.
+func (q *Query) GroupByCube(fields ...string) error {
+	return q.group("CUBE", fields)
+}
+
+func (q *Query) group(grouping string, fields []string) error {
+	paths := make([]string, len(fields))
+	for i, field := range fields {
+		paths[i] = field
+		for name, expr := range q.aliases {
+			if strings.EqualFold(name, field) {
+				paths[i] = expr
+			}
+		}
+		if !isFieldPath(paths[i]) {
+			return fmt.Errorf("query: cannot group by %v, it selects %v", field, paths[i])
+		}
+		if paths[i] == field && !q.knows(field) {
+			return fmt.Errorf("query: cannot group by %v, no such field", field)
+		}
+	}
+	q.grouping = grouping
+	q.groupBy = paths
+	return nil
+}
+
+// Reports whether s is a field path, such as "Owner.Name", rather than an expression like
+// Sum("Amount").
+func isFieldPath(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '.' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	}) < 0
+}
+
+// Adds a Constraint on the grouped results, usually on an aggregate expression:
+//
+//	q.Having(query.NewConstraint(query.Sum("Amount")).GreaterFloat(10000))
+//
+// All constraints added in this way are ANDed together.
+func (q *Query) Having(c Constraint) {
+	q.having = append(q.having, c)
+}
+
+// Counts the records matching the query's constraints with SELECT COUNT(), without fetching them.
+func (q *Query) Count() (int, error) {
+	return q.CountContext(context.Background())
+}
+
+// Like Count, but the query is bound to the given context.
+func (q *Query) CountContext(ctx context.Context) (int, error) {
+	table := simpleforce.SObjectType(reflect.TypeOf(q.dest).Elem().Elem())
+	soql := "SELECT COUNT() FROM " + table
+	if len(q.constraints) > 0 {
+		soql += " WHERE " + q.generateWhere()
+	}
+	it := q.force.QueryIterContext(ctx, soql)
+	var none struct{}
+	for it.Next(&none) {
+	}
+	return it.TotalSize(), it.Err()
+}
+
+func (q *Query) generateGroupBy() string {
+	fields := strings.Join(q.groupBy, ",")
+	if q.grouping != "" {
+		return q.grouping + "(" + fields + ")"
+	}
+	return fields
+}
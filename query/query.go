@@ -2,9 +2,11 @@ package query
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"bitbucket.org/jakebasile/simpleforce"
+	"github.com/jakebasile/simpleforce"
 	"reflect"
+	"strings"
 )
 
 // A Force.com query that constructs SOQL for you.
@@ -12,17 +14,42 @@ type Query struct {
 	force       simpleforce.Force
 	dest        interface{}
 	constraints []Constraint
+	aliases     map[string]string
+	groupBy     []string
+	grouping    string
+	having      []Constraint
+	orders      []order
+	children    map[string]*Query
+	subquery    bool
 	limit       int
+	offset      int
+}
+
+// Where NULL values go when sorting with OrderBy.
+type Nulls int
+
+const (
+	// Salesforce's default, which puts NULLs first in ascending order and last in descending order.
+	NullsDefault Nulls = iota
+	NullsFirst
+	NullsLast
+)
+
+// One column of an ORDER BY clause.
+type order struct {
+	field string
+	desc  bool
+	nulls Nulls
 }
 
 // Creates a new query for you to customize. When executed, this query will fill the given destination
 // slice with the results of the query.
 func New(f simpleforce.Force, dest interface{}) Query {
 	return Query{
-		f,
-		dest,
-		make([]Constraint, 0, 0),
-		10,
+		force:       f,
+		dest:        dest,
+		constraints: make([]Constraint, 0, 0),
+		limit:       10,
 	}
 }
 
@@ -35,63 +62,259 @@ func (q *Query) Limit(l int) {
 	q.limit = l
 }
 
+// Sorts the results by the given field, which may be a dotted path through parent relationships such
+// as "Account.Name", or an expression selected with Alias. Call it again to sort by more fields, in
+// the order they were added. Returns an error if the destination struct has no such field.
+func (q *Query) OrderBy(field string, desc bool, nulls ...Nulls) error {
+	if !q.knows(field) {
+		return fmt.Errorf("query: cannot order by %v, no such field", field)
+	}
+	o := order{field, desc, NullsDefault}
+	if len(nulls) > 0 {
+		o.nulls = nulls[0]
+	}
+	q.orders = append(q.orders, o)
+	return nil
+}
+
+// Skips the given number of results, for paging through them along with Limit and OrderBy.
+func (q *Query) Offset(n int) {
+	q.offset = n
+}
+
+// Returns the subquery for the given child relationship, a slice field of the destination struct such
+// as Contacts on an Account. Constraints, ordering and a limit set on the subquery apply to the
+// children of each record:
+//
+//	contacts, err := q.Child("Contacts")
+//	contacts.AddConstraint(query.NewConstraint("Email").NotEqualsNull())
+//	contacts.Limit(5)
+//
+// Every child relationship is fetched along with its parents whether or not Child is called for it;
+// without it, the subquery fetches all of the children. Returns an error if the struct has no such
+// child relationship. The subquery can't be run on its own.
+func (q *Query) Child(relationship string) (*Query, error) {
+	t := reflect.TypeOf(q.dest).Elem().Elem()
+	for i := 0; i < t.NumField() && !q.subquery; i++ {
+		field := t.Field(i)
+		info, ok := simpleforce.ParseField(field)
+		if !ok || !info.Children || !strings.EqualFold(info.Name, relationship) {
+			continue
+		}
+		if child, ok := q.children[info.Name]; ok {
+			return child, nil
+		}
+		if q.children == nil {
+			q.children = make(map[string]*Query)
+		}
+		child := newSubquery(q.force, field.Type)
+		q.children[info.Name] = child
+		return child, nil
+	}
+	return nil, fmt.Errorf("query: no child relationship %v", relationship)
+}
+
+// Creates the subquery for a child relationship held in a slice of the given type. Unlike a query,
+// it has no limit unless given one.
+func newSubquery(f simpleforce.Force, sliceType reflect.Type) *Query {
+	sub := New(f, reflect.New(sliceType).Interface())
+	sub.subquery = true
+	sub.limit = 0
+	return &sub
+}
+
 // Runs the query, depositing results in the destination given on query creation.
 func (q *Query) Run() error {
-	err := q.force.Query(q.Generate(), q.dest)
+	return q.RunContext(context.Background())
+}
+
+// Like Run, but the query is bound to the given context.
+func (q *Query) RunContext(ctx context.Context) error {
+	soql, err := q.Generate()
+	if err != nil {
+		return err
+	}
+	err = q.force.QueryContext(ctx, soql, q.dest)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// Constructs the SOQL that this query represents.
-func (q *Query) Generate() string {
-	sel := q.generateSelect()
-	table := reflect.TypeOf(q.dest).Elem().Elem().Name()
-	where := q.generateWhere()
-	var limit string
+// Constructs the SOQL that this query represents. Returns an error if the destination struct refers
+// back to itself through its parent relationships, or they go deeper than Salesforce allows.
+func (q *Query) Generate() (string, error) {
+	return q.generate(simpleforce.SObjectType(reflect.TypeOf(q.dest).Elem().Elem()))
+}
+
+// Constructs the SOQL for the query, selecting from the given sObject type or, for a subquery, child
+// relationship.
+func (q *Query) generate(table string) (string, error) {
+	sel, err := q.generateSelect()
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.NewBufferString(fmt.Sprintf("SELECT %v FROM %v", sel, table))
+	if len(q.constraints) > 0 {
+		buf.WriteString(" WHERE " + q.generateWhere())
+	}
+	if len(q.groupBy) > 0 {
+		buf.WriteString(" GROUP BY " + q.generateGroupBy())
+	}
+	if len(q.having) > 0 {
+		buf.WriteString(" HAVING " + collapseAll(q.having))
+	}
+	if len(q.orders) > 0 {
+		buf.WriteString(" ORDER BY " + q.generateOrderBy())
+	}
 	if q.limit > 0 {
-		limit = fmt.Sprintf(" LIMIT %v", q.limit)
-	} else {
-		limit = ""
+		fmt.Fprintf(buf, " LIMIT %v", q.limit)
 	}
-	return fmt.Sprintf("SELECT %v FROM %v WHERE %v%v", sel, table, where, limit)
+	if q.offset > 0 {
+		fmt.Fprintf(buf, " OFFSET %v", q.offset)
+	}
+	return buf.String(), nil
 }
 
-func (q *Query) generateSelect() string {
-	return genSelectForType(reflect.TypeOf(q.dest).Elem().Elem(), "")
+func (q *Query) generateSelect() (string, error) {
+	return q.genSelectForType(reflect.TypeOf(q.dest).Elem().Elem(), "", nil)
 }
 
-func genSelectForType(t reflect.Type, path string) string {
+// Lists the fields to select for t, each prefixed with path. At the top level, fields aliased with
+// Alias select their expression instead and child relationships select a subquery. Salesforce
+// doesn't allow subqueries below the top level or within a subquery, so child relationships there are
+// skipped. Parents holds the types of the records path passes through. Returns an error if t has
+// nothing left to select.
+func (q *Query) genSelectForType(t reflect.Type, path string, parents []reflect.Type) (string, error) {
+	for _, parent := range parents {
+		if parent == t {
+			return "", fmt.Errorf("query: %v refers back to %v; tag a field on the way force:\"-\" to break the cycle", path, t)
+		}
+	}
+	if len(parents) > simpleforce.MaxRelationshipDepth {
+		return "", fmt.Errorf("query: %v is more than %v relationships deep", path, simpleforce.MaxRelationshipDepth)
+	}
+	parents = append(parents, t)
 	buf := bytes.NewBufferString("")
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		if field.Type.Kind() == reflect.Ptr {
-			buf.WriteString(genSelectForType(field.Type.Elem(), field.Name))
+		info, ok := simpleforce.ParseField(field)
+		if !ok {
+			continue
+		}
+		name := info.Name
+		if len(path) > 0 {
+			name = path + "." + name
+		}
+		if info.Parent {
+			sel, err := q.genSelectForType(field.Type.Elem(), name, parents)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(sel)
 			buf.WriteString(",")
-		} else if field.Type.Kind() == reflect.Slice {
-			// wat do
-		} else {
-			if len(path) > 0 {
-				buf.WriteString(path + "." + field.Name)
-			} else {
-				buf.WriteString(field.Name)
+		} else if info.Children {
+			if len(path) > 0 || q.subquery {
+				continue
+			}
+			child, ok := q.children[info.Name]
+			if !ok {
+				child = newSubquery(q.force, field.Type)
 			}
+			sub, err := child.generate(info.Name)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString("(" + sub + ")")
+			buf.WriteString(",")
+		} else if expr, ok := q.aliases[info.Name]; ok && len(path) == 0 {
+			buf.WriteString(expr + " " + info.Name)
+			buf.WriteString(",")
+		} else {
+			buf.WriteString(name)
 			buf.WriteString(",")
 		}
 	}
 	s := buf.String()
+	if s == "" {
+		if path == "" {
+			return "", fmt.Errorf("query: %v has no fields to select", t)
+		}
+		return "", fmt.Errorf("query: %v has no fields to select; tag it force:\"-\" or give %v a field", path, t)
+	}
 	// drop last comma.
-	return s[:len(s)-1]
+	return s[:len(s)-1], nil
 }
 
 func (q *Query) generateWhere() string {
+	return collapseAll(q.constraints)
+}
+
+// Collapses each of the given constraints, ANDing them together.
+func collapseAll(cs []Constraint) string {
 	buf := bytes.NewBufferString("")
-	for i, c := range q.constraints {
+	for i, c := range cs {
 		buf.WriteString(c.Collapse())
-		if i < len(q.constraints)-1 {
+		if i < len(cs)-1 {
 			buf.WriteString(" AND ")
 		}
 	}
 	return buf.String()
 }
+
+func (q *Query) generateOrderBy() string {
+	buf := bytes.NewBufferString("")
+	for i, o := range q.orders {
+		buf.WriteString(o.field)
+		if o.desc {
+			buf.WriteString(" DESC")
+		}
+		switch o.nulls {
+		case NullsFirst:
+			buf.WriteString(" NULLS FIRST")
+		case NullsLast:
+			buf.WriteString(" NULLS LAST")
+		}
+		if i < len(q.orders)-1 {
+			buf.WriteString(",")
+		}
+	}
+	return buf.String()
+}
+
+// Reports whether the query can refer to the given field: a field of the destination struct, or an
+// expression selected with Alias.
+func (q *Query) knows(field string) bool {
+	for _, expr := range q.aliases {
+		if strings.EqualFold(expr, field) {
+			return true
+		}
+	}
+	return hasField(reflect.TypeOf(q.dest).Elem().Elem(), field)
+}
+
+// Reports whether the given path names a field of t, following parent relationships for each dotted
+// part but the last. Names are matched without regard to case, as they are in SOQL.
+func hasField(t reflect.Type, path string) bool {
+	names := strings.Split(path, ".")
+outer:
+	for i, name := range names {
+		for j := 0; j < t.NumField(); j++ {
+			field := t.Field(j)
+			info, ok := simpleforce.ParseField(field)
+			if !ok || !strings.EqualFold(info.Name, name) {
+				continue
+			}
+			if i == len(names)-1 {
+				return !info.Parent && !info.Children
+			}
+			if !info.Parent {
+				return false
+			}
+			t = field.Type.Elem()
+			continue outer
+		}
+		return false
+	}
+	return false
+}
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"github.com/jakebasile/simpleforce"
 	"github.com/jakebasile/simpleforce/query"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 type Account struct {
@@ -104,8 +108,407 @@ func TestSimpleQueryGeneration(t *testing.T) {
 	q.AddConstraint(query.NewConstraint("FirstName").EqualsString("Jake"))
 	q.AddConstraint(query.NewConstraint("LastName").EqualsString("Basile"))
 	q.AddConstraint(query.NewConstraint("Account.Name").EqualsString("Mutual Mobile"))
-	t.Log(q.Generate())
-	if q.Generate() != "SELECT FirstName,LastName,Name,Account.Name FROM Contact WHERE (FirstName='Jake') AND (LastName='Basile') AND (Account.Name='Mutual Mobile') LIMIT 10" {
+	soql, err := q.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(soql)
+	if soql != "SELECT FirstName,LastName,Name,Account.Name FROM Contact WHERE (FirstName='Jake') AND (LastName='Basile') AND (Account.Name='Mutual Mobile') LIMIT 10" {
+		t.Fail()
+	}
+}
+
+func TestTaggedQueryGeneration(t *testing.T) {
+	type User struct {
+		Name    string
+		Manager *Account `force:"Manager__r"`
+	}
+	type Opportunity struct {
+		_       struct{} `sobject:"Deal__c"`
+		Revenue float64  `force:"Annual_Revenue__c"`
+		Total   float64  `force:",readonly"`
+		Cache   string   `force:"-"`
+		Owner   *User    `force:"Owner__r"`
+		secret  string
+	}
+	var os []Opportunity
+	q := query.New(simpleforce.Force{}, &os)
+	q.AddConstraint(query.NewConstraint("Total").GreaterFloat(10))
+	soql, err := q.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(soql)
+	if soql != "SELECT Annual_Revenue__c,Total,Owner__r.Name,Owner__r.Manager__r.Name FROM Deal__c WHERE (Total>10) LIMIT 10" {
+		t.Fail()
+	}
+}
+
+func TestNullableQueryGeneration(t *testing.T) {
+	type Contact struct {
+		Title     *string
+		Birthdate *time.Time
+		Phone     simpleforce.NullString
+		Account   *Account
+	}
+	var cs []Contact
+	q := query.New(simpleforce.Force{}, &cs)
+	q.AddConstraint(query.NewConstraint("Title").NotEqualsNull())
+	soql, err := q.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(soql)
+	if soql != "SELECT Title,Birthdate,Phone,Account.Name FROM Contact WHERE (Title<>NULL) LIMIT 10" {
+		t.Fail()
+	}
+}
+
+func TestStringEscaping(t *testing.T) {
+	c := query.NewConstraint("LastName").EqualsString(`O'Brien \ "Jr."` + "\n")
+	t.Log(c.Collapse())
+	if c.Collapse() != `(LastName='O\'Brien \\ \"Jr.\"\n')` {
+		t.Fail()
+	}
+	c = query.NewConstraint("Name").LikeString("%" + query.EscapeLike(`50%_off\`) + "%")
+	t.Log(c.Collapse())
+	if c.Collapse() != `(Name LIKE '%50\%\_off\\%')` {
+		t.Fail()
+	}
+}
+
+// Reads the SOQL string literal at the start of s, returning its value and whatever follows it. In a
+// LIKE pattern the escapes \%, \_ and \\ are left in the value, since they mean something to LIKE.
+func parseLiteral(s string, like bool) (string, string, error) {
+	if !strings.HasPrefix(s, "'") {
+		return "", "", fmt.Errorf("no literal at %q", s)
+	}
+	var buf strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			return buf.String(), s[i+1:], nil
+		case '\n', '\r':
+			return "", "", fmt.Errorf("unescaped line break in %q", s)
+		case '\\':
+			i++
+			if i == len(s) {
+				break
+			}
+			switch s[i] {
+			case 'n', 'N':
+				buf.WriteByte('\n')
+			case 'r', 'R':
+				buf.WriteByte('\r')
+			case 't', 'T':
+				buf.WriteByte('\t')
+			case 'b', 'B':
+				buf.WriteByte('\b')
+			case 'f', 'F':
+				buf.WriteByte('\f')
+			case '\'', '"':
+				buf.WriteByte(s[i])
+			case '\\':
+				if like {
+					buf.WriteByte('\\')
+				}
+				buf.WriteByte('\\')
+			case '%', '_':
+				if !like {
+					return "", "", fmt.Errorf("wildcard escape outside LIKE in %q", s)
+				}
+				buf.WriteByte('\\')
+				buf.WriteByte(s[i])
+			default:
+				return "", "", fmt.Errorf("bad escape \\%c in %q", s[i], s)
+			}
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated literal %q", s)
+}
+
+func FuzzStringLiteral(f *testing.F) {
+	for _, seed := range []string{"Jake", "O'Brien", `back\slash`, "line\nbreak\r\n", `"quoted"`, "50%_off", `\%`, `\`, "'); DELETE FROM Contact"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		c := query.NewConstraint("Name").EqualsString(s)
+		clause := c.Collapse()
+		val, rest, err := parseLiteral(strings.TrimPrefix(clause, "(Name="), false)
+		if err != nil || val != s || rest != ")" {
+			t.Fatalf("%q: got %q, %q, %v", clause, val, rest, err)
+		}
+
+		c = query.NewConstraint("Name").InString(s, s)
+		clause = c.Collapse()
+		val, rest, err = parseLiteral(strings.TrimPrefix(clause, "(Name IN ("), false)
+		if err != nil || val != s || !strings.HasPrefix(rest, ",") {
+			t.Fatalf("%q: got %q, %q, %v", clause, val, rest, err)
+		}
+		val, rest, err = parseLiteral(rest[1:], false)
+		if err != nil || val != s || rest != "))" {
+			t.Fatalf("%q: got %q, %q, %v", clause, val, rest, err)
+		}
+
+		pattern := "%" + query.EscapeLike(s) + "%"
+		c = query.NewConstraint("Name").LikeString(pattern)
+		clause = c.Collapse()
+		val, rest, err = parseLiteral(strings.TrimPrefix(clause, "(Name LIKE "), true)
+		if err != nil || val != pattern || rest != ")" {
+			t.Fatalf("%q: got %q, %q, %v", clause, val, rest, err)
+		}
+	})
+}
+
+func TestOrderByAndOffset(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	type Contact struct {
+		LastName  string
+		Birthdate *time.Time
+		Owner     *User `force:"Owner__r"`
+	}
+	var cs []Contact
+	q := query.New(simpleforce.Force{}, &cs)
+	if err := q.OrderBy("LastName", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.OrderBy("Birthdate", true, query.NullsLast); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.OrderBy("owner__r.name", false, query.NullsFirst); err != nil {
+		t.Fatal(err)
+	}
+	q.Offset(20)
+	soql, err := q.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(soql)
+	if soql != "SELECT LastName,Birthdate,Owner__r.Name FROM Contact ORDER BY LastName,Birthdate DESC NULLS LAST,owner__r.name NULLS FIRST LIMIT 10 OFFSET 20" {
+		t.Fail()
+	}
+	for _, bad := range []string{"FirstName", "Owner__r", "Owner.Name", "LastName.Name", "Owner__r.Name.Length"} {
+		if err := q.OrderBy(bad, false); err == nil {
+			t.Errorf("expected an error ordering by %v", bad)
+		}
+	}
+}
+
+func TestAggregateQueryGeneration(t *testing.T) {
+	type StageTotal struct {
+		_         struct{} `sobject:"Opportunity"`
+		StageName string
+		OwnerName string  `force:"owner"`
+		Deals     int     `force:"deals"`
+		Total     float64 `force:"total"`
+	}
+	var ts []StageTotal
+	q := query.New(simpleforce.Force{}, &ts)
+	q.AddConstraint(query.NewConstraint("IsClosed").EqualsBool(false))
+	for _, err := range []error{
+		q.Alias("owner", "Owner.Name"),
+		q.Alias("Deals", query.Count("Id")),
+		q.Alias("total", query.Sum("Amount")),
+		q.GroupByRollup("StageName", "Owner.Name"),
+		q.OrderBy(query.Sum("Amount"), true),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.Having(query.NewConstraint(query.Sum("Amount")).GreaterFloat(10000))
+	q.Limit(0)
+	soql, err := q.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(soql)
+	if soql != "SELECT StageName,Owner.Name owner,COUNT(Id) deals,SUM(Amount) total FROM Opportunity WHERE (IsClosed=FALSE) GROUP BY ROLLUP(StageName,Owner.Name) HAVING (SUM(Amount)>10000) ORDER BY SUM(Amount) DESC" {
+		t.Fail()
+	}
+	if err := q.Alias("Missing", query.Max("Amount")); err == nil {
+		t.Error("expected an error aliasing a missing field")
+	}
+	if err := q.GroupBy("Probability"); err == nil {
+		t.Error("expected an error grouping by a missing field")
+	}
+	for _, aggregate := range []string{"total", "Deals", query.Sum("Amount")} {
+		if err := q.GroupBy(aggregate); err == nil {
+			t.Errorf("expected an error grouping by %v", aggregate)
+		}
+	}
+	if err := q.GroupBy("StageName", "owner"); err != nil {
+		t.Fatal(err)
+	}
+	soql, err = q.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(soql, " GROUP BY StageName,Owner.Name ") {
+		t.Errorf("expected owner to be grouped by its path, got %v", soql)
+	}
+}
+
+func TestAggregateResults(t *testing.T) {
+	type StageTotal struct {
+		_         struct{} `sobject:"Opportunity"`
+		StageName string
+		Deals     int     `force:"deals"`
+		Total     float64 `force:"expr0"`
+	}
+	var soql []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		soql = append(soql, r.URL.Query().Get("q"))
+		if strings.Contains(r.URL.Query().Get("q"), "COUNT()") {
+			fmt.Fprint(w, `{"totalSize":42,"done":true,"records":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"totalSize":2,"done":true,"records":[
+			{"attributes":{"type":"AggregateResult"},"StageName":"Prospecting","deals":3,"expr0":1500.5},
+			{"attributes":{"type":"AggregateResult"},"StageName":null,"deals":5,"expr0":2000}]}`)
+	}))
+	defer srv.Close()
+
+	var ts []StageTotal
+	q := query.New(simpleforce.New("session", srv.URL), &ts)
+	q.Alias("deals", query.Count("Id"))
+	q.Alias("expr0", query.Sum("Amount"))
+	q.GroupByRollup("StageName")
+	if err := q.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 2 || ts[0].StageName != "Prospecting" || ts[0].Deals != 3 || ts[0].Total != 1500.5 || ts[1].Deals != 5 {
+		t.Errorf("unexpected results %+v", ts)
+	}
+
+	q.AddConstraint(query.NewConstraint("IsWon").EqualsBool(true))
+	n, err := q.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 42 || soql[1] != "SELECT COUNT() FROM Opportunity WHERE (IsWon=TRUE)" {
+		t.Errorf("got %v from %q", n, soql[1])
+	}
+}
+
+func TestChildSubqueryGeneration(t *testing.T) {
+	type Case struct {
+		Subject string
+	}
+	type Contact struct {
+		LastName string
+		Email    string
+		Account  *Account
+		Cases    []Case
+	}
+	type Opportunity struct {
+		Name string
+	}
+	type Account struct {
+		Name          string
+		Contacts      []Contact
+		Opportunities []Opportunity `force:"Deals__r"`
+	}
+	var as []Account
+	q := query.New(simpleforce.Force{}, &as)
+	contacts, err := q.Child("contacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contacts.AddConstraint(query.NewConstraint("Email").NotEqualsNull())
+	if err := contacts.OrderBy("LastName", false); err != nil {
+		t.Fatal(err)
+	}
+	contacts.Limit(5)
+	if again, _ := q.Child("Contacts"); again != contacts {
+		t.Error("expected the same subquery for the same relationship")
+	}
+	if _, err := q.Child("Name"); err == nil {
+		t.Error("expected an error for a field that isn't a child relationship")
+	}
+	if _, err := contacts.Child("Cases"); err == nil {
+		t.Error("expected an error nesting subqueries")
+	}
+	soql, err := q.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(soql)
+	if soql != "SELECT Name,(SELECT LastName,Email,Account.Name FROM Contacts WHERE (Email<>NULL) ORDER BY LastName LIMIT 5),(SELECT Name FROM Deals__r) FROM Account LIMIT 10" {
 		t.Fail()
 	}
 }
+
+type CyclicAccount struct {
+	Name           string
+	PrimaryContact *CyclicContact
+}
+
+type CyclicContact struct {
+	Name    string
+	Account *CyclicAccount
+}
+
+func TestCircularReferences(t *testing.T) {
+	var cs []CyclicContact
+	q := query.New(simpleforce.Force{}, &cs)
+	if soql, err := q.Generate(); err == nil {
+		t.Errorf("expected an error for a circular reference, got %v", soql)
+	} else {
+		t.Log(err)
+	}
+
+	type Profile struct{ Name string }
+	type Manager struct{ Profile *Profile }
+	type Owner struct{ Manager *Manager }
+	type Parent struct{ Owner *Owner }
+	type Account struct{ Parent *Parent }
+	type Contact struct{ Account *Account }
+	var deep []Contact
+	q = query.New(simpleforce.Force{}, &deep)
+	if _, err := q.Generate(); err != nil {
+		t.Error(err)
+	}
+	type Case struct{ Contact *Contact }
+	var deeper []Case
+	q = query.New(simpleforce.Force{}, &deeper)
+	if soql, err := q.Generate(); err == nil {
+		t.Errorf("expected an error for six relationships, got %v", soql)
+	} else {
+		t.Log(err)
+	}
+}
+
+type EmptyAccount struct {
+	Contacts []EmptyContact
+}
+
+type EmptyContact struct {
+	Name    string
+	Account *EmptyAccount
+}
+
+func TestEmptyParent(t *testing.T) {
+	var cs []EmptyContact
+	q := query.New(simpleforce.Force{}, &cs)
+	if soql, err := q.Generate(); err == nil {
+		t.Errorf("expected an error for a parent with no fields, got %v", soql)
+	} else {
+		t.Log(err)
+	}
+
+	type Ignored struct {
+		Cache string `force:"-"`
+	}
+	var is []Ignored
+	q = query.New(simpleforce.Force{}, &is)
+	if soql, err := q.Generate(); err == nil {
+		t.Errorf("expected an error for a struct with no fields, got %v", soql)
+	} else {
+		t.Log(err)
+	}
+}
@@ -2,47 +2,48 @@ package query
 
 import (
 	"bytes"
+	"strings"
 )
 
 // Creates an '=' clause for a string value.
 func (c Constraint) EqualsString(right string) Constraint {
 	c.op = "="
-	c.right = "'" + right + "'"
+	c.right = quoteString(right)
 	return c
 }
 
 // Creates a '<>' clause for a string value.
 func (c Constraint) NotEqualsString(right string) Constraint {
 	c.op = "<>"
-	c.right = "'" + right + "'"
+	c.right = quoteString(right)
 	return c
 }
 
 // Creates a '>' clause for a string value.
 func (c Constraint) GreaterString(right string) Constraint {
 	c.op = ">"
-	c.right = "'" + right + "'"
+	c.right = quoteString(right)
 	return c
 }
 
 // Creates a '>=' clause for a string value.
 func (c Constraint) GreaterEqualsString(right string) Constraint {
 	c.op = ">="
-	c.right = "'" + right + "'"
+	c.right = quoteString(right)
 	return c
 }
 
 // Creates a '<' clause for a string value.
 func (c Constraint) LessString(right string) Constraint {
 	c.op = "<"
-	c.right = "'" + right + "'"
+	c.right = quoteString(right)
 	return c
 }
 
 // Creates a '<=' clause for a string value.
 func (c Constraint) LessEqualsString(right string) Constraint {
 	c.op = "<="
-	c.right = "'" + right + "'"
+	c.right = quoteString(right)
 	return c
 }
 
@@ -51,7 +52,7 @@ func (c Constraint) InString(in ...string) Constraint {
 	c.op = " IN "
 	buf := bytes.NewBufferString("(")
 	for i, s := range in {
-		buf.WriteString("'" + s + "'")
+		buf.WriteString(quoteString(s))
 		if i < len(in)-1 {
 			buf.WriteString(",")
 		}
@@ -66,7 +67,7 @@ func (c Constraint) NotInString(in ...string) Constraint {
 	c.op = " NOT IN "
 	buf := bytes.NewBufferString("(")
 	for i, s := range in {
-		buf.WriteString("'" + s + "'")
+		buf.WriteString(quoteString(s))
 		if i < len(in)-1 {
 			buf.WriteString(",")
 		}
@@ -76,9 +77,56 @@ func (c Constraint) NotInString(in ...string) Constraint {
 	return c
 }
 
-// Creates a LIKE clause for a string value.
+// Creates a LIKE clause for a string pattern, in which % matches any run of characters and _ any
+// single character, and \%, \_ and \\ match a literal %, _ and backslash. Escape values taken from
+// user input with EscapeLike so they match literally:
+//
+//	c.LikeString("%" + query.EscapeLike(input) + "%")
 func (c Constraint) LikeString(like string) Constraint {
 	c.op = " LIKE "
-	c.right = "'" + like + "'"
+	c.right = quoteLike(like)
 	return c
 }
+
+var (
+	stringEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		`'`, `\'`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+		"\b", `\b`,
+		"\f", `\f`,
+	)
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// Escapes backslashes and the LIKE wildcards in s, so that it matches itself literally when used in
+// a LikeString pattern.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// Returns s as a SOQL string literal, with quotes, backslashes and control characters escaped.
+func quoteString(s string) string {
+	return "'" + stringEscaper.Replace(s) + "'"
+}
+
+// Like quoteString, but the escape sequences \%, \_ and \\ that may appear in a LIKE pattern are kept
+// as they are.
+func quoteLike(s string) string {
+	buf := bytes.NewBufferString("'")
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`%_\`, s[i+1]) >= 0 {
+			buf.WriteString(stringEscaper.Replace(s[start:i]))
+			buf.WriteString(s[i : i+2])
+			i++
+			start = i + 1
+		}
+	}
+	buf.WriteString(stringEscaper.Replace(s[start:]))
+	buf.WriteString("'")
+	return buf.String()
+}
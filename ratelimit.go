@@ -0,0 +1,163 @@
+package simpleforce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Returned when a request would exceed the daily budget set with WithRateLimit.
+var ErrDailyBudgetExhausted = errors.New("simpleforce: daily API call budget exhausted")
+
+// Limits on the API calls a Force makes, shared by every copy of it and so by every goroutine using
+// it. Zero fields impose no limit.
+type RateLimit struct {
+	// The sustained number of calls allowed per second.
+	PerSecond float64
+	// How many calls may be made at once after a quiet period, before PerSecond applies. Defaults to 1.
+	Burst int
+	// The most calls that may be in progress at the same time.
+	MaxInFlight int
+	// The most calls allowed in each 24 hour period, counted from the first call. Calls beyond it fail
+	// with ErrDailyBudgetExhausted rather than waiting.
+	DailyBudget int
+}
+
+// Makes every API call wait until the given limits allow it. A call whose context is done while it
+// waits fails with the context's error.
+func WithRateLimit(limit RateLimit) Option {
+	return func(f *Force) {
+		f.limiter = newLimiter(limit)
+	}
+}
+
+type limiter struct {
+	limit    RateLimit
+	inFlight chan struct{}
+
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	budgetStart time.Time
+	budgetUsed  int
+}
+
+func newLimiter(limit RateLimit) *limiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	l := &limiter{
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}
+	if limit.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, limit.MaxInFlight)
+	}
+	return l
+}
+
+// Waits until a call may be made, returning a function to call once it has finished. Calls over the
+// daily budget fail before waiting on anything, and the in-flight slot is taken last, so a call
+// doesn't hold one while it waits for the rate to allow it.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := l.spendBudget(); err != nil {
+		return nil, err
+	}
+	if err := l.waitForToken(ctx); err != nil {
+		l.refundBudget()
+		return nil, err
+	}
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.inFlight <- struct{}{}:
+	case <-ctx.Done():
+		l.refundToken()
+		l.refundBudget()
+		return nil, ctx.Err()
+	}
+	return func() { <-l.inFlight }, nil
+}
+
+func (l *limiter) spendBudget() error {
+	if l.limit.DailyBudget <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.budgetStart.IsZero() || now.Sub(l.budgetStart) >= 24*time.Hour {
+		l.budgetStart = now
+		l.budgetUsed = 0
+	}
+	if l.budgetUsed >= l.limit.DailyBudget {
+		return ErrDailyBudgetExhausted
+	}
+	l.budgetUsed++
+	return nil
+}
+
+// Gives back budget spent on a call that was never made.
+func (l *limiter) refundBudget() {
+	if l.limit.DailyBudget <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.budgetUsed > 0 {
+		l.budgetUsed--
+	}
+}
+
+// Puts back a token taken for a call that was never made.
+func (l *limiter) refundToken() {
+	if l.limit.PerSecond <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+	if l.tokens > float64(l.limit.Burst) {
+		l.tokens = float64(l.limit.Burst)
+	}
+}
+
+// Takes a token from the bucket, waiting for one to be added if it's empty.
+func (l *limiter) waitForToken(ctx context.Context) error {
+	if l.limit.PerSecond <= 0 {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.limit.PerSecond
+		if l.tokens > float64(l.limit.Burst) {
+			l.tokens = float64(l.limit.Burst)
+		}
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.limit.PerSecond * float64(time.Second))
+		l.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
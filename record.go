@@ -0,0 +1,107 @@
+package simpleforce
+
+import (
+	"strings"
+	"time"
+)
+
+// A record whose fields aren't known until run time, as in admin tools and ad-hoc exports. Query,
+// QueryIter and Get decode into Records as well as structs:
+//
+//	var rs []simpleforce.Record
+//	err := f.Query("SELECT Name, Account.Name FROM Contact", &rs)
+//	for _, r := range rs {
+//		fmt.Println(r.GetString("Name"), r.GetString("Account.Name"))
+//	}
+//
+// Results can also be decoded into a map[string]interface{} per record, which holds the same values
+// with parent relationships as nested maps, child relationships as slices of them and the attributes
+// left as they came.
+type Record struct {
+	Attributes Attributes
+	// Field values by API name. Values are decoded the way encoding/json decodes into an interface{},
+	// except that parent relationships are a *Record and child relationships are a []Record.
+	Fields map[string]interface{}
+}
+
+// The attributes Salesforce sends along with every record.
+type Attributes struct {
+	// The sObject type, such as "Contact".
+	Type string `json:"type"`
+	// The REST URL of the record, relative to the instance.
+	Url string `json:"url"`
+}
+
+// Returns the value at the given path, and whether the record has it. Paths follow parent
+// relationships with dots, as in "Account.Owner.Name". Names are matched without regard to case,
+// as they are in SOQL.
+func (r *Record) Get(path string) (interface{}, bool) {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		r = r.GetRecord(name)
+	}
+	if r == nil {
+		return nil, false
+	}
+	if v, ok := r.Fields[names[len(names)-1]]; ok {
+		return v, true
+	}
+	for name, v := range r.Fields {
+		if strings.EqualFold(name, names[len(names)-1]) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Returns the string at the given path, or the empty string if it is missing, NULL or not a string.
+func (r *Record) GetString(path string) string {
+	v, _ := r.Get(path)
+	s, _ := v.(string)
+	return s
+}
+
+// Returns the number at the given path, or zero if it is missing, NULL or not a number.
+func (r *Record) GetFloat(path string) float64 {
+	v, _ := r.Get(path)
+	n, _ := v.(float64)
+	return n
+}
+
+// Returns the number at the given path as a whole number, or zero if it is missing, NULL or not a
+// number.
+func (r *Record) GetInt(path string) int64 {
+	return int64(r.GetFloat(path))
+}
+
+// Returns the boolean at the given path, or false if it is missing, NULL or not a boolean.
+func (r *Record) GetBool(path string) bool {
+	v, _ := r.Get(path)
+	b, _ := v.(bool)
+	return b
+}
+
+// Returns the date or datetime at the given path, or the zero time if it is missing, NULL or not a
+// date.
+func (r *Record) GetTime(path string) time.Time {
+	t, err := parseTime(r.GetString(path))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Returns the parent record at the given path, or nil if it is missing or NULL. The accessors are
+// safe to call on the nil Record, so lookups can be chained.
+func (r *Record) GetRecord(path string) *Record {
+	v, _ := r.Get(path)
+	parent, _ := v.(*Record)
+	return parent
+}
+
+// Returns the child records at the given path, or nil if there are none.
+func (r *Record) GetRecords(path string) []Record {
+	v, _ := r.Get(path)
+	children, _ := v.([]Record)
+	return children
+}
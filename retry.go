@@ -0,0 +1,114 @@
+package simpleforce
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"syscall"
+	"time"
+)
+
+// Decides which failed requests are sent again and how long to wait in between. Waits grow
+// exponentially from MinBackoff up to MaxBackoff, with random jitter so that many clients failing at
+// once don't retry in lockstep.
+type RetryPolicy struct {
+	// The most times a request is sent, including the first. Values below 2 disable retries.
+	MaxAttempts int
+	MinBackoff  time.Duration
+	// The longest wait between attempts, or zero for no limit.
+	MaxBackoff time.Duration
+	// The HTTP methods that may be retried. Only idempotent reads are retried by default; add POST,
+	// PATCH or DELETE to retry writes too, at the risk of applying one twice.
+	Methods []string
+	// The Salesforce error codes worth retrying, whatever the status.
+	ErrorCodes []string
+	// The HTTP statuses worth retrying, whatever the error code.
+	StatusCodes []int
+	// Waits for the given duration or until the context is done. Defaults to a real timer; tests can
+	// substitute a fake clock.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Returns the policy every Force starts with: up to three attempts for GET requests that fail with a
+// 5xx status, a row lock, the request limit, or a dropped connection.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+		Methods:     []string{"GET", "HEAD"},
+		ErrorCodes:  []string{"REQUEST_LIMIT_EXCEEDED", "UNABLE_TO_LOCK_ROW", "SERVER_UNAVAILABLE"},
+		StatusCodes: []int{500, 502, 503, 504},
+	}
+}
+
+// Replaces the default retry policy. Pass the zero RetryPolicy to disable retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(f *Force) {
+		f.retry = policy
+	}
+}
+
+// Calls attempt until it succeeds, fails in a way that isn't worth retrying, or runs out of attempts.
+func (p RetryPolicy) do(ctx context.Context, method string, attempt func() (int, []byte, error)) (int, []byte, error) {
+	for n := 1; ; n++ {
+		status, body, err := attempt()
+		if err == nil || n >= p.MaxAttempts || !p.retryable(method, err) {
+			return status, body, err
+		}
+		if sleepErr := p.sleep(ctx, p.backoff(n)); sleepErr != nil {
+			return status, body, err
+		}
+	}
+}
+
+// Reports whether a request with the given method that failed with err should be sent again.
+func (p RetryPolicy) retryable(method string, err error) bool {
+	if !contains(p.Methods, method) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if contains(p.ErrorCodes, apiErr.ErrorCode) {
+			return true
+		}
+		for _, status := range p.StatusCodes {
+			if status == apiErr.StatusCode {
+				return true
+			}
+		}
+		return false
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// Returns how long to wait after the given failed attempt: an exponentially growing ceiling, of which
+// a random amount between half and all is used.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempt && i < 32 && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (p RetryPolicy) sleep(ctx context.Context, d time.Duration) error {
+	if p.Sleep != nil {
+		return p.Sleep(ctx, d)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,120 @@
+package simpleforce_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/jakebasile/simpleforce"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// A retry policy that records its waits instead of sleeping.
+func fakeClockPolicy(waits *[]time.Duration) simpleforce.RetryPolicy {
+	p := simpleforce.DefaultRetryPolicy()
+	p.MaxAttempts = 4
+	p.MinBackoff = time.Second
+	p.MaxBackoff = 3 * time.Second
+	p.Sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return p
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	failures := 3
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `[{"errorCode":"SERVER_UNAVAILABLE","message":"try again"}]`)
+			return
+		}
+		fmt.Fprint(w, `{"totalSize":1,"done":true,"records":[{"Name":"Jake"}]}`)
+	}))
+	defer srv.Close()
+
+	var waits []time.Duration
+	f := simpleforce.New("session", srv.URL, simpleforce.WithRetryPolicy(fakeClockPolicy(&waits)))
+	var cs []Contact
+	if err := f.Query("SELECT Name FROM Contact", &cs); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 4 || len(cs) != 1 {
+		t.Errorf("expected success on the fourth attempt, got %v requests and %v", requests, cs)
+	}
+	ceilings := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if len(waits) != len(ceilings) {
+		t.Fatalf("unexpected waits %v", waits)
+	}
+	for i, w := range waits {
+		if w < ceilings[i]/2 || w > ceilings[i] {
+			t.Errorf("wait %v of %v outside [%v, %v]", i, w, ceilings[i]/2, ceilings[i])
+		}
+	}
+
+	requests, failures, waits = 0, 10, nil
+	err := f.Query("SELECT Name FROM Contact", &cs)
+	if apiErr, ok := err.(*simpleforce.APIError); !ok || apiErr.ErrorCode != "SERVER_UNAVAILABLE" {
+		t.Errorf("expected to give up with the last error, got %v", err)
+	}
+	if requests != 4 {
+		t.Errorf("expected four attempts, got %v", requests)
+	}
+}
+
+func TestRetryWritesOnlyWhenAllowed(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `[{"errorCode":"UNABLE_TO_LOCK_ROW","message":"unable to obtain exclusive access to this record"}]`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var waits []time.Duration
+	policy := fakeClockPolicy(&waits)
+	f := simpleforce.New("session", srv.URL, simpleforce.WithRetryPolicy(policy))
+	if err := f.Delete("Contact", "003"); err == nil {
+		t.Error("expected the write not to be retried")
+	}
+
+	requests = 0
+	policy.Methods = append(policy.Methods, "DELETE")
+	f = simpleforce.New("session", srv.URL, simpleforce.WithRetryPolicy(policy))
+	if err := f.Delete("Contact", "003"); err != nil {
+		t.Errorf("expected the write to be retried, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected two attempts, got %v", requests)
+	}
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	policy := simpleforce.DefaultRetryPolicy()
+	policy.MinBackoff = time.Hour
+	f := simpleforce.New("session", srv.URL, simpleforce.WithRetryPolicy(policy))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var cs []Contact
+	if err := f.QueryContext(ctx, "SELECT Name FROM Contact", &cs); err == nil {
+		t.Error("expected an error")
+	}
+	if requests != 1 {
+		t.Errorf("expected to stop waiting after one attempt, got %v", requests)
+	}
+}
@@ -0,0 +1,233 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Creates a new record from the given struct or struct pointer, returning the Id Salesforce assigned
+// to it. The sObject type is found with SObjectType. Fields are named the same way they are when
+// reading query results, honoring force tags as described under Field. Relationship pointers, child
+// slices, read-only fields and the Id field are not sent, nor are fields tagged omitempty that hold
+// their zero value.
+func (f Force) Create(src interface{}) (string, error) {
+	return f.CreateContext(context.Background(), src)
+}
+
+// Like Create, but the request is bound to the given context.
+func (f Force) CreateContext(ctx context.Context, src interface{}) (string, error) {
+	val := reflect.Indirect(reflect.ValueOf(src))
+	if val.Kind() != reflect.Struct {
+		return "", errors.New("simpleforce: Create needs a struct or a pointer to a struct")
+	}
+	obj, err := marshalIndividualObject(val, false, nil)
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		Id string `json:"id"`
+	}
+	_, err = f.sendJson(ctx, "POST", f.dataUrl()+"/sobjects/"+SObjectType(val.Type())+"/", obj, &created)
+	if err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+// Fetches a single record by Id into the given pointer to a struct, Record or map. If no fields are
+// given, Salesforce returns every field on the record and, for a struct, only those matching it are
+// kept.
+func (f Force) Get(sobjectType, id string, dest interface{}, fields ...string) error {
+	return f.GetContext(context.Background(), sobjectType, id, dest, fields...)
+}
+
+// Like Get, but the request is bound to the given context.
+func (f Force) GetContext(ctx context.Context, sobjectType, id string, dest interface{}, fields ...string) error {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() || !isDecodable(destVal.Elem().Type()) {
+		return errors.New("simpleforce: Get needs a pointer to a struct, Record or map")
+	}
+	u := f.dataUrl() + "/sobjects/" + sobjectType + "/" + url.PathEscape(id)
+	if len(fields) > 0 {
+		vals := url.Values{}
+		vals.Set("fields", strings.Join(fields, ","))
+		u += "?" + vals.Encode()
+	}
+	var record map[string]json.RawMessage
+	if _, err := f.sendJson(ctx, "GET", u, nil, &record); err != nil {
+		return err
+	}
+	val := reflect.New(destVal.Elem().Type()).Elem()
+	if err := f.unmarshalRecord(ctx, record, val); err != nil {
+		return err
+	}
+	destVal.Elem().Set(val)
+	return nil
+}
+
+// Updates the record with the given Id from a struct or struct pointer. Only fields with non-zero
+// values are sent, along with any fields named explicitly by their API names, so a zero value can
+// still be written by naming its field.
+func (f Force) Update(sobjectType, id string, src interface{}, fields ...string) error {
+	return f.UpdateContext(context.Background(), sobjectType, id, src, fields...)
+}
+
+// Like Update, but the request is bound to the given context.
+func (f Force) UpdateContext(ctx context.Context, sobjectType, id string, src interface{}, fields ...string) error {
+	val := reflect.Indirect(reflect.ValueOf(src))
+	if val.Kind() != reflect.Struct {
+		return errors.New("simpleforce: Update needs a struct or a pointer to a struct")
+	}
+	u := f.dataUrl() + "/sobjects/" + sobjectType + "/" + url.PathEscape(id)
+	obj, err := marshalIndividualObject(val, true, fields)
+	if err != nil {
+		return err
+	}
+	_, err = f.sendJson(ctx, "PATCH", u, obj, nil)
+	return err
+}
+
+// Creates or updates the record whose external Id field holds the given value, depending on whether
+// one exists yet. The struct is sent the same way Create sends it, except for the external Id field
+// itself, which goes in the URL. Returns true if a new record was created.
+func (f Force) Upsert(sobjectType, externalIdField, externalIdValue string, src interface{}) (bool, error) {
+	return f.UpsertContext(context.Background(), sobjectType, externalIdField, externalIdValue, src)
+}
+
+// Like Upsert, but the request is bound to the given context.
+func (f Force) UpsertContext(ctx context.Context, sobjectType, externalIdField, externalIdValue string, src interface{}) (bool, error) {
+	val := reflect.Indirect(reflect.ValueOf(src))
+	if val.Kind() != reflect.Struct {
+		return false, errors.New("simpleforce: Upsert needs a struct or a pointer to a struct")
+	}
+	obj, err := marshalIndividualObject(val, false, nil)
+	if err != nil {
+		return false, err
+	}
+	delete(obj, externalIdField)
+	u := f.dataUrl() + "/sobjects/" + sobjectType + "/" + url.PathEscape(externalIdField) + "/" + url.PathEscape(externalIdValue)
+	status, err := f.sendJson(ctx, "PATCH", u, obj, nil)
+	if err != nil {
+		return false, err
+	}
+	return status == http.StatusCreated, nil
+}
+
+// Deletes the record with the given Id.
+func (f Force) Delete(sobjectType, id string) error {
+	return f.DeleteContext(context.Background(), sobjectType, id)
+}
+
+// Like Delete, but the request is bound to the given context.
+func (f Force) DeleteContext(ctx context.Context, sobjectType, id string) error {
+	u := f.dataUrl() + "/sobjects/" + sobjectType + "/" + url.PathEscape(id)
+	_, err := f.sendJson(ctx, "DELETE", u, nil, nil)
+	return err
+}
+
+// Turns a struct into the field map sent to Salesforce. This is the inverse of
+// unmarshalIndividualObject, minus the fields Salesforce won't let us write. When partial is set,
+// fields holding their zero value are left out unless they are named in include.
+func marshalIndividualObject(val reflect.Value, partial bool, include []string) (map[string]interface{}, error) {
+	if !val.CanAddr() {
+		// Marshalers with pointer receivers need an addressable copy to be found.
+		addressable := reflect.New(val.Type()).Elem()
+		addressable.Set(val)
+		val = addressable
+	}
+	obj := make(map[string]interface{})
+	for _, info := range planFor(val.Type()) {
+		if info.ReadOnly || info.Parent || info.Children || info.Name == "Id" {
+			continue
+		}
+		field := val.Field(info.index)
+		if field.IsZero() && !contains(include, info.Name) && (partial || info.OmitEmpty) {
+			continue
+		}
+		v, ok, err := marshalValue(field, info.Date)
+		if err != nil {
+			return nil, fmt.Errorf("simpleforce: encoding %v: %v", info.Name, err)
+		}
+		if ok {
+			obj[info.Name] = v
+		}
+	}
+	return obj, nil
+}
+
+// Turns a single field value into what's sent for it, the inverse of unmarshalValue. Marshalers are
+// asked first. Nil pointers, invalid Null types and zero times are sent as NULL. Times are sent in
+// DateFormat when date is set. Returns false for kinds that can't be sent.
+func marshalValue(field reflect.Value, date bool) (interface{}, bool, error) {
+	if m, ok := asMarshaler(field); ok {
+		b, err := m.MarshalForce()
+		if err != nil {
+			return nil, false, err
+		}
+		return json.RawMessage(b), true, nil
+	}
+	switch field.Kind() {
+	case reflect.Bool:
+		return field.Bool(), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int(), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field.Uint(), true, nil
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), true, nil
+	case reflect.String:
+		return field.String(), true, nil
+	case reflect.Ptr:
+		if field.IsNil() {
+			return nil, true, nil
+		}
+		return marshalValue(field.Elem(), date)
+	case reflect.Struct:
+		switch v := field.Interface().(type) {
+		case time.Time:
+			if v.IsZero() {
+				return nil, true, nil
+			}
+			return formatTime(v, date), true, nil
+		case NullString:
+			if !v.Valid {
+				return nil, true, nil
+			}
+			return v.String, true, nil
+		case NullFloat:
+			if !v.Valid {
+				return nil, true, nil
+			}
+			return v.Float, true, nil
+		case NullTime:
+			if !v.Valid {
+				return nil, true, nil
+			}
+			return formatTime(v.Time, date), true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func formatTime(t time.Time, date bool) string {
+	if date {
+		return t.Format(DateFormat)
+	}
+	return t.Format(DateTimeFormat)
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
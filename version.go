@@ -0,0 +1,71 @@
+package simpleforce
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// A version of the REST API available on an instance.
+type Version struct {
+	Label   string `json:"label"`
+	Url     string `json:"url"`
+	Version string `json:"version"`
+}
+
+// Makes the Force use the given REST API version, such as "58.0", instead of DefaultVersion.
+func WithVersion(version string) Option {
+	return func(f *Force) {
+		f.version = version
+	}
+}
+
+// Returns the REST API version this Force uses.
+func (f Force) Version() string {
+	return f.version
+}
+
+// Lists the REST API versions the instance supports, oldest first.
+func (f Force) Versions() ([]Version, error) {
+	return f.VersionsContext(context.Background())
+}
+
+// Like Versions, but the request is bound to the given context.
+func (f Force) VersionsContext(ctx context.Context) ([]Version, error) {
+	var versions []Version
+	if _, err := f.sendJson(ctx, "GET", f.instance+"/services/data", nil, &versions); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versionNumber(versions[i]) < versionNumber(versions[j])
+	})
+	return versions, nil
+}
+
+// Returns the version as a number, such as 58.0, or -1 if it isn't one.
+func versionNumber(v Version) float64 {
+	n, err := strconv.ParseFloat(v.Version, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// Returns a copy of this Force that uses the newest REST API version the instance supports.
+func (f Force) UseLatestVersion() (Force, error) {
+	return f.UseLatestVersionContext(context.Background())
+}
+
+// Like UseLatestVersion, but the request is bound to the given context.
+func (f Force) UseLatestVersionContext(ctx context.Context) (Force, error) {
+	versions, err := f.VersionsContext(ctx)
+	if err != nil {
+		return f, err
+	}
+	if len(versions) == 0 || versionNumber(versions[len(versions)-1]) < 0 {
+		return f, errors.New("simpleforce: instance reported no API versions")
+	}
+	f.version = versions[len(versions)-1].Version
+	return f, nil
+}